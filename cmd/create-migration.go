@@ -8,13 +8,19 @@ import (
 	"github.com/Juksefantomet/gecho/internal/migrate"
 )
 
+var createGoMigration bool
+
 var createMigrationCmd = &cobra.Command{
 	Use:   "create-migration <name>",
 	Short: "Create empty up/down SQL migration files",
 	Args:  cobra.ExactArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
 		name := args[0]
-		if err := migrate.Create(name); err != nil {
+		create := migrate.Create
+		if createGoMigration {
+			create = migrate.CreateGo
+		}
+		if err := create(name); err != nil {
 			fmt.Fprintf(os.Stderr, "✗ Migration creation failed: %v\n", err)
 			os.Exit(1)
 		}
@@ -22,5 +28,6 @@ var createMigrationCmd = &cobra.Command{
 }
 
 func init() {
+	createMigrationCmd.Flags().BoolVar(&createGoMigration, "go", false, "Create a Go migration stub instead of SQL files")
 	rootCmd.AddCommand(createMigrationCmd)
 }
@@ -0,0 +1,230 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+
+	"github.com/Juksefantomet/gecho/internal/env"
+	"github.com/Juksefantomet/gecho/internal/runner"
+	"github.com/Juksefantomet/gecho/internal/schema"
+	"github.com/Juksefantomet/gecho/internal/tool/services/database"
+	"github.com/Juksefantomet/gecho/seed"
+)
+
+// seedDir is where gecho projects keep their seed files, with environment
+// specific ones in sub-directories named after the environment.
+const seedDir = "db/seeds"
+
+var dbCmd = &cobra.Command{
+	Use:   "db",
+	Short: "Manage the project database",
+}
+
+var dbCreateCmd = &cobra.Command{
+	Use:   "create",
+	Short: "Create the configured database if it does not exist",
+	Args:  cobra.NoArgs,
+	Run:   runDBCommand(runDBCreate),
+}
+
+var dbDropCmd = &cobra.Command{
+	Use:   "drop",
+	Short: "Drop the configured database (refused on protected environments)",
+	Args:  cobra.NoArgs,
+	Run:   runDBCommand(runDBDrop),
+}
+
+var dbResetCmd = &cobra.Command{
+	Use:   "reset",
+	Short: "Drop, create, migrate and seed the configured database",
+	Args:  cobra.NoArgs,
+	Run:   runDBCommand(runDBReset),
+}
+
+var dbSetupCmd = &cobra.Command{
+	Use:   "setup",
+	Short: "Create the configured database if it is missing, then migrate it",
+	Args:  cobra.NoArgs,
+	Run:   runDBCommand(runDBSetup),
+}
+
+var dbSeedCmd = &cobra.Command{
+	Use:   "seed",
+	Short: "Run the seeds in " + seedDir + " that have not run yet",
+	Args:  cobra.NoArgs,
+	Run:   runDBCommand(runDBSeed),
+}
+
+var dbSchemaLoadCmd = &cobra.Command{
+	Use:   "schema:load",
+	Short: "Create the schema of a fresh database from " + schema.DefaultPath,
+	Args:  cobra.NoArgs,
+	Run:   runDBCommand(runSchemaLoad),
+}
+
+func init() {
+	dbCmd.AddCommand(dbCreateCmd)
+	dbCmd.AddCommand(dbDropCmd)
+	dbCmd.AddCommand(dbResetCmd)
+	dbCmd.AddCommand(dbSetupCmd)
+	dbCmd.AddCommand(dbSeedCmd)
+	dbCmd.AddCommand(dbSchemaLoadCmd)
+	rootCmd.AddCommand(dbCmd)
+}
+
+// runDBCommand adapts run to a cobra Run func, exiting with the same codes
+// as gecho migrate so failed migrations during reset and setup are
+// recognizable.
+func runDBCommand(run func() error) func(cmd *cobra.Command, args []string) {
+	return func(cmd *cobra.Command, args []string) {
+		if err := run(); err != nil {
+			fmt.Fprintf(os.Stderr, "✗ %v\n", err)
+			os.Exit(migrateExitCode(err))
+		}
+	}
+}
+
+func runDBCreate() error {
+	name, err := database.Name()
+	if err != nil {
+		return err
+	}
+	created, err := database.CreateDatabase()
+	if err != nil {
+		return err
+	}
+	if created {
+		fmt.Printf("✓ Database %s created.\n", name)
+	} else {
+		fmt.Printf("Database %s already exists.\n", name)
+	}
+	return nil
+}
+
+func runDBDrop() error {
+	name, err := database.Name()
+	if err != nil {
+		return err
+	}
+	if env.Protected() {
+		return fmt.Errorf("refusing to drop database %s: environment %q is protected (GECHO_PROTECTED=true)", name, env.Name())
+	}
+	dropped, err := database.DropDatabase()
+	if err != nil {
+		return err
+	}
+	if dropped {
+		fmt.Printf("✓ Database %s dropped.\n", name)
+	} else {
+		fmt.Printf("Database %s does not exist.\n", name)
+	}
+	return nil
+}
+
+func runDBReset() error {
+	dirs := append([]string{migrationDir}, seedDirs()...)
+	if runner.Needed(dirs...) {
+		return runner.Exec(dirs...)
+	}
+	if err := runDBDrop(); err != nil {
+		return err
+	}
+	if err := runDBCreate(); err != nil {
+		return err
+	}
+	if err := migrateAll(); err != nil {
+		return err
+	}
+	return seedAll()
+}
+
+func runDBSetup() error {
+	if runner.Needed(migrationDir) {
+		return runner.Exec(migrationDir)
+	}
+	if err := runDBCreate(); err != nil {
+		return err
+	}
+	return migrateAll()
+}
+
+// migrateAll applies every pending migration, as a plain `gecho migrate`.
+func migrateAll() error {
+	if err := database.InitDB(); err != nil {
+		return err
+	}
+	db, err := database.GetDB()
+	if err != nil {
+		return err
+	}
+	if err := database.EnsureSchema(); err != nil {
+		return err
+	}
+
+	fmt.Println("Running migrations...")
+	if err := newMigrator(db).Up(0); err != nil {
+		return err
+	}
+	fmt.Println("Migrations complete.")
+	dumpSchema(db)
+	return nil
+}
+
+func runDBSeed() error {
+	if runner.Needed(seedDirs()...) {
+		return runner.Exec(seedDirs()...)
+	}
+	if err := database.InitDB(); err != nil {
+		return err
+	}
+	return seedAll()
+}
+
+// seedAll runs the pending seeds of the selected environment on the
+// initialized connection.
+func seedAll() error {
+	db, err := database.GetDB()
+	if err != nil {
+		return err
+	}
+	if err := database.EnsureSchema(); err != nil {
+		return err
+	}
+
+	fmt.Printf("Seeding (%s)...\n", env.Name())
+	n, err := seed.New(db, os.DirFS(seedDir), seed.Options{Env: env.Name()}).Run()
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Seeding complete, %d seed(s) ran.\n", n)
+	return nil
+}
+
+// seedDirs lists the directories holding the seeds of the selected
+// environment.
+func seedDirs() []string {
+	return []string{seedDir, filepath.Join(seedDir, env.Name())}
+}
+
+func runSchemaLoad() error {
+	if err := database.InitDB(); err != nil {
+		return err
+	}
+	db, err := database.GetDB()
+	if err != nil {
+		return err
+	}
+	if err := database.EnsureSchema(); err != nil {
+		return err
+	}
+
+	fmt.Printf("Loading %s...\n", schema.DefaultPath)
+	if err := schema.Load(db, schema.DefaultPath); err != nil {
+		return fmt.Errorf("schema load failed: %w", err)
+	}
+	fmt.Println("Schema loaded.")
+	return nil
+}
@@ -0,0 +1,60 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/Juksefantomet/gecho/internal/scaffold"
+	"github.com/Juksefantomet/gecho/internal/tool/services/database"
+	"github.com/Juksefantomet/gecho/migrate"
+	"gorm.io/gorm"
+)
+
+var destroyForce bool
+
+var destroyCmd = &cobra.Command{
+	Use:   "destroy <name>",
+	Short: "Remove the files and routes generated by gecho scaffold <name>",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		err := scaffold.Destroy(args[0], scaffold.DestroyOptions{
+			Force:   destroyForce,
+			Applied: migrationApplied(),
+		})
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "✗ Destroy failed: %v\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	destroyCmd.Flags().BoolVar(&destroyForce, "force", false,
+		"Also remove files edited since they were generated, migrations that were applied, and resources others reference")
+	rootCmd.AddCommand(destroyCmd)
+}
+
+// migrationApplied returns a lookup of the migrations table over a
+// read-only connection, opened on first use.
+func migrationApplied() func(name string) (bool, error) {
+	var db *gorm.DB
+	return func(name string) (bool, error) {
+		if db == nil {
+			if err := database.InitReadOnlyDB(); err != nil {
+				return false, err
+			}
+			conn, err := database.GetDB()
+			if err != nil {
+				return false, err
+			}
+			db = conn
+		}
+		if !db.Migrator().HasTable(migrate.DefaultTableName) {
+			return false, nil
+		}
+		var count int64
+		err := db.Table(migrate.DefaultTableName).Where("name = ?", name).Count(&count).Error
+		return count > 0, err
+	}
+}
@@ -1,53 +1,369 @@
 package cmd
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/tabwriter"
 
-	"github.com/joho/godotenv"
 	"github.com/spf13/cobra"
+	"gorm.io/gorm"
 
+	"github.com/Juksefantomet/gecho/internal/env"
+	"github.com/Juksefantomet/gecho/internal/runner"
+	"github.com/Juksefantomet/gecho/internal/schema"
 	"github.com/Juksefantomet/gecho/internal/tool/services/database"
+	"github.com/Juksefantomet/gecho/migrate"
+)
+
+// migrationDir is where gecho projects keep their migration files.
+const migrationDir = "db/migrations"
+
+// Exit codes of gecho migrate, so deploy scripts can tell failures apart.
+const (
+	exitError           = 1
+	exitMigrationFailed = 2
+	exitMissingDown     = 3
+	exitDrift           = 4
+	exitLockTimeout     = 5
+)
+
+var (
+	migrateStatusJSON  bool
+	migrateStep        int
+	migrateDryRun      bool
+	migrateOutput      string
+	migrateLockTimeout = migrate.DefaultLockTimeout
+	migrateAllowDrift  bool
+	migrateSkipDump    bool
 )
 
 var migrateCmd = &cobra.Command{
-	Use:   "migrate [down|help]",
+	Use:   "migrate [up [N]|down [N]|to <version>|status|repair|help]",
 	Short: "Run or rollback database migrations",
-	Args:  cobra.MaximumNArgs(1),
+	Args:  cobra.MaximumNArgs(2),
 	Run: func(cmd *cobra.Command, args []string) {
-		_ = godotenv.Load(".env")
-		database.InitDB()
-		db := database.GetDB()
-
-		if len(args) == 0 {
-			fmt.Println("Running migrations...")
-			database.RunMigrations(db)
-			fmt.Println("Migrations complete.")
+		if len(args) > 0 && args[0] == "help" {
+			printMigrateHelp()
 			return
 		}
 
-		switch args[0] {
-		case "down":
-			fmt.Println("Rolling back last migration...")
-			database.RollbackLastMigration(db)
-			fmt.Println("Rollback complete.")
-		case "help":
-			printMigrateHelp()
-		default:
-			printMigrateHelp()
-			fmt.Fprintf(os.Stderr, "\n✗ Unknown argument: %s\n", args[0])
-			os.Exit(1)
+		if err := runMigrate(args); err != nil {
+			fmt.Fprintf(os.Stderr, "✗ %v\n", err)
+			os.Exit(migrateExitCode(err))
 		}
 	},
 }
 
 func init() {
+	migrateCmd.Flags().DurationVar(&migrateLockTimeout, "lock-timeout", migrateLockTimeout,
+		"How long to wait for another migration run to release its lock")
+	migrateCmd.Flags().BoolVar(&migrateAllowDrift, "allow-drift", false,
+		"Continue even if applied migrations were modified since they ran")
+	migrateCmd.Flags().BoolVar(&migrateSkipDump, "skip-schema-dump", false,
+		"Do not rewrite "+schema.DefaultPath+" after migrating")
+	migrateCmd.Flags().BoolVar(&migrateStatusJSON, "json", false, "Print migrate status as JSON")
+	migrateCmd.Flags().IntVar(&migrateStep, "step", 0, "Roll back this many migrations instead of the last batch")
+	migrateCmd.Flags().Lookup("step").NoOptDefVal = "1"
+	migrateCmd.Flags().BoolVar(&migrateDryRun, "dry-run", false, "Print the SQL that would run without touching the database")
+	migrateCmd.Flags().StringVar(&migrateOutput, "output", "", "Write the dry-run plan to this file as a single BEGIN/COMMIT script")
 	rootCmd.AddCommand(migrateCmd)
 }
 
+func runMigrate(args []string) error {
+	if runner.Needed(migrationDir) {
+		return runner.Exec(migrationDir)
+	}
+
+	action := ""
+	if len(args) > 0 {
+		action = args[0]
+	}
+
+	dryRun := migrateDryRun || migrateOutput != ""
+	if !dryRun && (action == "down" || action == "to") {
+		if err := env.Confirm("run migrate "+action, assumeYes); err != nil {
+			return err
+		}
+	}
+
+	connect := database.InitDB
+	if dryRun {
+		connect = database.InitReadOnlyDB
+	}
+	if err := connect(); err != nil {
+		return err
+	}
+	db, err := database.GetDB()
+	if err != nil {
+		return err
+	}
+
+	m := newMigrator(db)
+
+	if dryRun {
+		return runMigrateDryRun(m, action, args)
+	}
+	if err := database.EnsureSchema(); err != nil {
+		return err
+	}
+
+	switch action {
+	case "", "up":
+		n, err := migrateCount(args)
+		if err != nil {
+			return err
+		}
+		fmt.Println("Running migrations...")
+		if err := m.Up(n); err != nil {
+			return err
+		}
+		fmt.Println("Migrations complete.")
+		dumpSchema(db)
+	case "down":
+		n, err := rollbackCount(args)
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			fmt.Println("Rolling back last batch...")
+		} else {
+			fmt.Printf("Rolling back %d migration(s)...\n", n)
+		}
+		if err := m.Down(n); err != nil {
+			return err
+		}
+		fmt.Println("Rollback complete.")
+		dumpSchema(db)
+	case "to":
+		if len(args) != 2 {
+			return errors.New("migrate to requires a version, e.g. gecho migrate to 20250101120000")
+		}
+		fmt.Printf("Migrating to version %s...\n", args[1])
+		if err := m.To(args[1]); err != nil {
+			return err
+		}
+		fmt.Println("Migration complete.")
+		dumpSchema(db)
+	case "status":
+		statuses, err := m.Status()
+		if err != nil {
+			return err
+		}
+		return printMigrateStatus(statuses)
+	case "repair":
+		fmt.Println("Re-recording migration checksums...")
+		n, err := m.Repair()
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Repair complete, %d checksum(s) updated.\n", n)
+	default:
+		printMigrateHelp()
+		return fmt.Errorf("unknown argument: %s", action)
+	}
+	return nil
+}
+
+// newMigrator returns a migrator over the project's migrations configured
+// by the migrate flags.
+func newMigrator(db *gorm.DB) *migrate.Migrator {
+	return migrate.New(db, os.DirFS(migrationDir), migrate.Options{
+		LockTimeout: migrateLockTimeout,
+		AllowDrift:  migrateAllowDrift,
+	})
+}
+
+// dumpSchema rewrites db/schema.sql after a successful run. The migrations
+// already committed, so a failed dump is reported without failing the run.
+func dumpSchema(db *gorm.DB) {
+	if migrateSkipDump {
+		return
+	}
+	if err := schema.WriteFile(db, schema.DefaultPath); err != nil {
+		fmt.Fprintf(os.Stderr, "⚠ Failed to update %s: %v\n", schema.DefaultPath, err)
+		return
+	}
+	fmt.Printf("Updated %s.\n", schema.DefaultPath)
+}
+
+// migrateExitCode maps engine errors to the exit codes documented in
+// printMigrateHelp.
+func migrateExitCode(err error) int {
+	var (
+		failed      *migrate.ErrMigrationFailed
+		missingDown *migrate.ErrMissingDown
+		drift       *migrate.ErrDrift
+		lockTimeout *migrate.ErrLockTimeout
+	)
+	switch {
+	case errors.As(err, &failed):
+		return exitMigrationFailed
+	case errors.As(err, &missingDown):
+		return exitMissingDown
+	case errors.As(err, &drift):
+		return exitDrift
+	case errors.As(err, &lockTimeout):
+		return exitLockTimeout
+	default:
+		return exitError
+	}
+}
+
+func printMigrateStatus(statuses []migrate.MigrationStatus) error {
+	if migrateStatusJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(statuses); err != nil {
+			return fmt.Errorf("failed to encode status: %w", err)
+		}
+		return nil
+	}
+
+	if len(statuses) == 0 {
+		fmt.Println("No migrations found.")
+		return nil
+	}
+
+	counts := make(map[migrate.MigrationState]int)
+	missingDown, modified := 0, 0
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "STATUS\tMIGRATION\tBATCH\tAPPLIED AT\tNOTES")
+	for _, s := range statuses {
+		counts[s.State]++
+		appliedAt, batch := "-", "-"
+		if s.AppliedAt != nil {
+			batch = strconv.Itoa(s.Batch)
+			appliedAt = s.AppliedAt.Format("2006-01-02 15:04:05")
+		}
+		var notes []string
+		if s.Modified {
+			notes = append(notes, "checksum mismatch")
+			modified++
+		}
+		if !s.HasDown {
+			notes = append(notes, "missing .down.sql")
+			missingDown++
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", s.State, s.Name, batch, appliedAt, strings.Join(notes, ", "))
+	}
+	w.Flush()
+
+	fmt.Printf("\n%d applied, %d pending, %d orphaned, %d modified, %d missing .down.sql\n",
+		counts[migrate.StateApplied], counts[migrate.StatePending],
+		counts[migrate.StateOrphaned], modified, missingDown)
+	if modified > 0 {
+		fmt.Println("Run `gecho migrate repair` if the modifications were intentional.")
+	}
+	return nil
+}
+
+// runMigrateDryRun prints, or writes to --output, the migrations that
+// `migrate`, `migrate up` or `migrate down` would run.
+func runMigrateDryRun(m *migrate.Migrator, action string, args []string) error {
+	var (
+		steps []migrate.PlanStep
+		n     int
+		err   error
+	)
+	switch action {
+	case "", "up":
+		if n, err = migrateCount(args); err == nil {
+			steps, err = m.PlanUp(n)
+		}
+	case "down":
+		if n, err = rollbackCount(args); err == nil {
+			steps, err = m.PlanDown(n)
+		}
+	default:
+		return fmt.Errorf("--dry-run is not supported for migrate %s", action)
+	}
+	if err != nil {
+		return err
+	}
+
+	if migrateOutput != "" {
+		f, err := os.Create(migrateOutput)
+		if err != nil {
+			return fmt.Errorf("failed to create %s: %w", migrateOutput, err)
+		}
+		defer f.Close()
+		if err := migrate.WritePlanScript(f, steps); err != nil {
+			return fmt.Errorf("failed to write %s: %w", migrateOutput, err)
+		}
+		fmt.Printf("Wrote plan for %d migration(s) to %s\n", len(steps), migrateOutput)
+		return nil
+	}
+
+	if len(steps) == 0 {
+		fmt.Println("Nothing to do.")
+		return nil
+	}
+	for _, step := range steps {
+		if step.Setup != "" {
+			fmt.Printf("%s\n\n", step.Setup)
+		}
+		sql := strings.TrimRight(step.SQL, "\n")
+		if step.Go {
+			sql = "-- Go migration, runs its registered func"
+		}
+		fmt.Printf("-- %s\n%s\n\n", filepath.Join(migrationDir, step.File), sql)
+	}
+	fmt.Printf("Dry run: %d migration(s) would run, nothing was executed.\n", len(steps))
+	return nil
+}
+
+// rollbackCount returns how many migrations `migrate down` should undo,
+// honoring --step, or 0 for the whole last batch.
+func rollbackCount(args []string) (int, error) {
+	n, err := migrateCount(args)
+	if err != nil || n > 0 {
+		return n, err
+	}
+	return migrateStep, nil
+}
+
+// migrateCount parses the optional N of `migrate up N` / `migrate down N`,
+// returning 0 when it is omitted.
+func migrateCount(args []string) (int, error) {
+	if len(args) < 2 {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(args[1])
+	if err != nil || n < 1 {
+		return 0, fmt.Errorf("invalid migration count: %s", args[1])
+	}
+	return n, nil
+}
+
 func printMigrateHelp() {
 	fmt.Println("Usage:")
-	fmt.Println("  gecho migrate         # Apply all pending migrations")
-	fmt.Println("  gecho migrate down    # Roll back the last migration")
-	fmt.Println("  gecho migrate help    # Show this help message")
+	fmt.Println("  gecho migrate                # Apply all pending migrations")
+	fmt.Println("  gecho migrate up [N]         # Apply the next N pending migrations (default: all)")
+	fmt.Println("  gecho migrate down           # Roll back every migration from the last run")
+	fmt.Println("  gecho migrate down --step    # Roll back only the last migration")
+	fmt.Println("  gecho migrate down N         # Roll back the last N migrations")
+	fmt.Println("  gecho migrate to <version>   # Apply or roll back until <version> is the latest applied")
+	fmt.Println("  gecho migrate --dry-run      # Print the pending SQL without executing it (also for down)")
+	fmt.Println("  gecho migrate --dry-run --output plan.sql")
+	fmt.Println("                               # Write the plan as one BEGIN/COMMIT script")
+	fmt.Println("  gecho migrate status         # Show applied, pending and orphaned migrations")
+	fmt.Println("  gecho migrate status --json  # Same, as JSON for CI")
+	fmt.Println("  gecho migrate repair         # Re-record checksums after editing applied migrations")
+	fmt.Println("  gecho migrate help           # Show this help message")
+	fmt.Println()
+	fmt.Println("After migrate, migrate down and migrate to, db/schema.sql is rewritten")
+	fmt.Println("from the database (skip with --skip-schema-dump).")
+	fmt.Println()
+	fmt.Println("Exit codes:")
+	fmt.Println("  1  other error")
+	fmt.Println("  2  a migration's SQL failed")
+	fmt.Println("  3  a .down.sql needed for rollback is missing")
+	fmt.Println("  4  applied migrations were modified (see --allow-drift)")
+	fmt.Println("  5  timed out waiting for the migration lock")
 }
@@ -5,11 +5,21 @@ import (
 	"os"
 
 	"github.com/spf13/cobra"
+
+	"github.com/Juksefantomet/gecho/internal/env"
+)
+
+var (
+	envName   string
+	assumeYes bool
 )
 
 var rootCmd = &cobra.Command{
 	Use:   "gecho",
 	Short: "Gecho – Scaffold and migrate Echo-based Go projects",
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		return env.Load(envName)
+	},
 }
 
 func init() {
@@ -17,15 +27,31 @@ func init() {
 
 Available Commands:
   init                 Initialize a new project structure
-  scaffold <name>      Generate model, route, query, and migration
+  scaffold <name> [field:type...] [--dry-run|--diff] [--force]
+                       Generate model, route, query, and migration
+  destroy <name>       Remove what scaffold <name> generated
+  templates eject      Copy the built-in templates into .gecho/templates
   create-migration     Create blank SQL migration files
   migrate [down]       Apply or roll back migrations
-  version			   Print the current Gecho version
+  db create|drop       Create or drop the configured database
+  db setup             Create the database if missing, then migrate
+  db reset             Drop, create, migrate and seed the database
+  db seed              Run the seeds in db/seeds that have not run yet
+  db schema:load       Create a fresh database's schema from db/schema.sql
+  version              Print the current Gecho version
+
+Global Flags:
+  --env <name>         Load .env.<name> before .env (or set GECHO_ENV)
+  -y, --yes            Skip confirmation for destructive commands on
+                       environments marked GECHO_PROTECTED=true
 
 Use "gecho [command] --help" for more information about a command.
 
-Note: Generating scaffolds does not enable route in main.go.
+Scaffolded routes are registered in main.go automatically.
 `)
+	rootCmd.PersistentFlags().StringVar(&envName, "env", "", "Environment to load from .env.<name> (default $GECHO_ENV)")
+	rootCmd.PersistentFlags().BoolVarP(&assumeYes, "yes", "y", false,
+		"Skip confirmation for destructive commands on protected environments")
 	rootCmd.AddCommand(initCmd)
 	rootCmd.AddCommand(scaffoldCmd)
 	rootCmd.AddCommand(createMigrationCmd)
@@ -8,13 +8,45 @@ import (
 	"github.com/Juksefantomet/gecho/internal/scaffold"
 )
 
+var (
+	scaffoldDryRun bool
+	scaffoldDiff   bool
+	scaffoldForce  bool
+)
+
 var scaffoldCmd = &cobra.Command{
-	Use:   "scaffold <name>",
+	Use:   "scaffold <name> [field:type[:modifier]...]",
 	Short: "Generate model, routes, migrations, and query boilerplate",
-	Args:  cobra.ExactArgs(1),
+	Long: `Generate model, routes, migrations, and query boilerplate.
+
+Fields are given as name:type[:modifier...], e.g.
+
+  gecho scaffold product name:string:unique price:decimal description:text:null
+
+Types:     string, text, int, bigint, bool, decimal, time, date, uuid, jsonb,
+           references (or belongs_to)
+Modifiers: unique, index, null, default=<value> (last, may contain colons),
+           on_delete=cascade|restrict|set_null (references only)
+
+  gecho scaffold comment post:references:on_delete=cascade body:text
+
+adds a post_id column referencing posts(id), a Post association to the
+model and GET /posts/:post_id/comments.
+
+Without fields the table gets a unique name column.
+
+The name may be PascalCase, camelCase, snake_case, kebab-case or spaced:
+BlogPost, blog-post and "blog post" all give the BlogPost model, the
+blog_posts table and /blog_posts routes. Go keywords are rejected, as
+are field names that are Postgres reserved words (order, user, group).`,
+	Args: cobra.MinimumNArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
 		name := args[0]
-		err := scaffold.Run(name)
+		err := scaffold.Run(name, args[1:], scaffold.Options{
+			DryRun: scaffoldDryRun,
+			Diff:   scaffoldDiff,
+			Force:  scaffoldForce,
+		})
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "✗ Scaffold failed: %v\n", err)
 			os.Exit(1)
@@ -23,5 +55,8 @@ var scaffoldCmd = &cobra.Command{
 }
 
 func init() {
+	scaffoldCmd.Flags().BoolVar(&scaffoldDryRun, "dry-run", false, "Print the files that would be written, with their content, and write nothing")
+	scaffoldCmd.Flags().BoolVar(&scaffoldDiff, "diff", false, "Print a unified diff against the existing files and write nothing")
+	scaffoldCmd.Flags().BoolVar(&scaffoldForce, "force", false, "Overwrite files that already exist, the resource's migration included")
 	rootCmd.AddCommand(scaffoldCmd)
 }
@@ -0,0 +1,47 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/Juksefantomet/gecho/internal/templates"
+)
+
+var ejectForce bool
+
+var templatesCmd = &cobra.Command{
+	Use:   "templates",
+	Short: "Manage the templates gecho init and gecho scaffold generate files from",
+	Long: `Manage the templates gecho init and gecho scaffold generate files from.
+
+A file in .gecho/templates replaces the built-in template of the same name,
+e.g. .gecho/templates/scaffold/model.go.tmpl. Templates use Go's
+text/template; see "gecho templates eject" to start from the defaults.`,
+}
+
+var templatesEjectCmd = &cobra.Command{
+	Use:   "eject",
+	Short: "Copy the built-in templates into .gecho/templates for editing",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		written, err := templates.Eject(ejectForce)
+		if len(written) > 0 {
+			fmt.Printf("Ejected:\n  %s\n", strings.Join(written, "\n  "))
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "✗ Eject failed: %v\n", err)
+			os.Exit(1)
+		}
+		if len(written) == 0 {
+			fmt.Printf("All templates already exist in %s (use --force to overwrite them).\n", templates.OverrideDir)
+		}
+	},
+}
+
+func init() {
+	templatesEjectCmd.Flags().BoolVar(&ejectForce, "force", false, "Overwrite templates already in .gecho/templates")
+	templatesCmd.AddCommand(templatesEjectCmd)
+	rootCmd.AddCommand(templatesCmd)
+}
@@ -0,0 +1,86 @@
+package env
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/joho/godotenv"
+)
+
+// name is the environment selected by Load, "" for the default .env.
+var name string
+
+// Load selects the environment (the --env flag, else GECHO_ENV) and loads
+// .env.<name> followed by .env, so values missing from the environment
+// specific file fall back to .env. Variables already set in the process
+// always win.
+func Load(flag string) error {
+	name = flag
+	if name == "" {
+		name = os.Getenv("GECHO_ENV")
+	}
+
+	if name != "" {
+		file := ".env." + name
+		if _, err := os.Stat(file); err == nil {
+			if err := godotenv.Load(file); err != nil {
+				return fmt.Errorf("failed to load %s: %w", file, err)
+			}
+		} else {
+			fmt.Fprintf(os.Stderr, "⚠ %s not found, falling back to .env\n", file)
+		}
+		os.Setenv("GECHO_ENV", name)
+	}
+
+	if _, err := os.Stat(".env"); err == nil {
+		if err := godotenv.Load(".env"); err != nil {
+			return fmt.Errorf("failed to load .env: %w", err)
+		}
+	}
+	return nil
+}
+
+// Name returns the selected environment, or "default" when none was given.
+func Name() string {
+	if name == "" {
+		return "default"
+	}
+	return name
+}
+
+// Protected reports whether the loaded environment is marked protected by
+// GECHO_PROTECTED=true in its env file.
+func Protected() bool {
+	return os.Getenv("GECHO_PROTECTED") == "true"
+}
+
+// Confirm guards a destructive action against a protected environment.
+// It passes when the environment is unprotected or yes is set, otherwise
+// it asks for the environment name to be typed on an interactive terminal.
+func Confirm(action string, yes bool) error {
+	if !Protected() || yes {
+		return nil
+	}
+
+	if !isTerminal(os.Stdin) {
+		return fmt.Errorf("environment %q is protected; pass --yes to %s", Name(), action)
+	}
+	return confirm(action, os.Stdin, os.Stdout)
+}
+
+func confirm(action string, in io.Reader, out io.Writer) error {
+	fmt.Fprintf(out, "⚠ Environment %q is protected. Type its name to %s: ", Name(), action)
+	answer, _ := bufio.NewReader(in).ReadString('\n')
+	if strings.TrimSpace(answer) != Name() {
+		return fmt.Errorf("aborted: confirmation did not match %q", Name())
+	}
+	return nil
+}
+
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
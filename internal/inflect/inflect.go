@@ -0,0 +1,256 @@
+// Package inflect turns English nouns into their plural and singular
+// forms. Every name gecho derives from a resource name (tables, URL paths,
+// handler and file names) goes through it, so they always agree.
+//
+// A project adds its own words in File:
+//
+//	{
+//	  "irregular":   {"cactus": "cacti"},
+//	  "uncountable": ["staff"],
+//	  "plural":      [["(quiz)$", "${1}zes"]],
+//	  "singular":    [["(quiz)zes$", "${1}"]]
+//	}
+//
+// Rules are regular expressions with regexp.ReplaceAllString replacements.
+// Project words and rules take precedence over the built-in ones.
+package inflect
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// File holds a project's custom inflections.
+const File = ".gecho/inflections.json"
+
+type rule struct {
+	re          *regexp.Regexp
+	replacement string
+}
+
+// Rules inflects words. The zero value knows no words; use New.
+type Rules struct {
+	plurals     []rule // tried in order, first match wins
+	singulars   []rule
+	irregular   map[string]string // singular → plural
+	irregularOf map[string]string // plural → singular
+	uncountable map[string]bool
+}
+
+// New returns the built-in English rules.
+func New() *Rules {
+	r := &Rules{
+		irregular:   make(map[string]string),
+		irregularOf: make(map[string]string),
+		uncountable: make(map[string]bool),
+	}
+	for _, p := range defaultPlurals {
+		r.plurals = append(r.plurals, rule{regexp.MustCompile(p[0]), p[1]})
+	}
+	for _, s := range defaultSingulars {
+		r.singulars = append(r.singulars, rule{regexp.MustCompile(s[0]), s[1]})
+	}
+	for _, pair := range defaultIrregulars {
+		r.Irregular(pair[0], pair[1])
+	}
+	r.Uncountable(defaultUncountables...)
+	return r
+}
+
+// Load returns the built-in rules extended with the project's File, if it
+// exists.
+func Load() (*Rules, error) {
+	r := New()
+	if err := r.LoadFile(File); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+	return r, nil
+}
+
+// LoadFile adds the inflections in the JSON file at path, see the package
+// documentation for its format.
+func (r *Rules) LoadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var file struct {
+		Irregular   map[string]string `json:"irregular"`
+		Uncountable []string          `json:"uncountable"`
+		Plural      [][2]string       `json:"plural"`
+		Singular    [][2]string       `json:"singular"`
+	}
+	if err := json.Unmarshal(data, &file); err != nil {
+		return fmt.Errorf("invalid %s: %w", path, err)
+	}
+
+	for singular, plural := range file.Irregular {
+		r.Irregular(singular, plural)
+	}
+	r.Uncountable(file.Uncountable...)
+	// Added in reverse so the first rule in the file is tried first.
+	for i := len(file.Plural) - 1; i >= 0; i-- {
+		if err := r.Plural(file.Plural[i][0], file.Plural[i][1]); err != nil {
+			return fmt.Errorf("invalid %s: %w", path, err)
+		}
+	}
+	for i := len(file.Singular) - 1; i >= 0; i-- {
+		if err := r.Singular(file.Singular[i][0], file.Singular[i][1]); err != nil {
+			return fmt.Errorf("invalid %s: %w", path, err)
+		}
+	}
+	return nil
+}
+
+// Irregular adds a word whose plural follows no rule.
+func (r *Rules) Irregular(singular, plural string) {
+	singular, plural = strings.ToLower(singular), strings.ToLower(plural)
+	r.irregular[singular] = plural
+	r.irregularOf[plural] = singular
+}
+
+// Uncountable adds words that are the same in singular and plural.
+func (r *Rules) Uncountable(words ...string) {
+	for _, w := range words {
+		r.uncountable[strings.ToLower(w)] = true
+	}
+}
+
+// Plural adds a pluralization rule, tried before the existing ones.
+func (r *Rules) Plural(pattern, replacement string) error {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return err
+	}
+	r.plurals = append([]rule{{re, replacement}}, r.plurals...)
+	return nil
+}
+
+// Singular adds a singularization rule, tried before the existing ones.
+func (r *Rules) Singular(pattern, replacement string) error {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return err
+	}
+	r.singulars = append([]rule{{re, replacement}}, r.singulars...)
+	return nil
+}
+
+// Pluralize returns the plural of word. Only the last part of a snake_case
+// word is inflected: order_item becomes order_items.
+func (r *Rules) Pluralize(word string) string {
+	return r.inflect(word, r.irregular, r.irregularOf, r.plurals)
+}
+
+// Singularize returns the singular of word, the inverse of Pluralize.
+// Words that already are singular are returned unchanged.
+func (r *Rules) Singularize(word string) string {
+	return r.inflect(word, r.irregularOf, r.irregular, r.singulars)
+}
+
+// inflect changes the last word of s into the wanted form: through to if
+// it is an irregular word, unchanged if it is a key of from (irregular and
+// already in the wanted form), and by the first matching rule otherwise.
+func (r *Rules) inflect(s string, to, from map[string]string, rules []rule) string {
+	prefix, word := "", s
+	if i := strings.LastIndex(s, "_"); i >= 0 {
+		prefix, word = s[:i+1], s[i+1:]
+	}
+	lower := strings.ToLower(word)
+	if lower == "" || r.uncountable[lower] {
+		return s
+	}
+
+	var out string
+	if w, ok := to[lower]; ok {
+		out = w
+	} else if _, ok := from[lower]; ok {
+		out = lower // already in the wanted form
+	} else {
+		out = lower
+		for _, rl := range rules {
+			if rl.re.MatchString(lower) {
+				out = rl.re.ReplaceAllString(lower, rl.replacement)
+				break
+			}
+		}
+	}
+
+	if first, _ := utf8.DecodeRuneInString(word); unicode.IsUpper(first) {
+		out = strings.ToUpper(out[:1]) + out[1:]
+	}
+	return prefix + out
+}
+
+var defaultPlurals = [][2]string{
+	{`^(m|l)ouse$`, "${1}ice"},
+	{`^(ox)$`, "${1}en"},
+	{`(quiz)$`, "${1}zes"},
+	{`(matr)ix$`, "${1}ices"},
+	{`(vert|ind)ex$`, "${1}ices"},
+	{`(her|potat|tomat|ech|vet|torped|volcan)o$`, "${1}oes"},
+	{`sis$`, "ses"},
+	{`(x|ch|sh|ss|zz)$`, "${1}es"},
+	{`s$`, "ses"}, // status, bus, alias, lens
+	{`([^aeiouy]|qu)y$`, "${1}ies"},
+	{`$`, "s"},
+}
+
+var defaultSingulars = [][2]string{
+	{`^(m|l)ice$`, "${1}ouse"},
+	{`^(ox)en$`, "${1}"},
+	{`(quiz)zes$`, "${1}"},
+	{`(matr)ices$`, "${1}ix"},
+	{`(vert|ind)ices$`, "${1}ex"},
+	{`(her|potat|tomat|ech|vet|torped|volcan)oes$`, "${1}o"},
+	{`^(analy|diagno|parenthe|progno|synop|the|hypothe|empha|cri|oa)(sis|ses)$`, "${1}sis"},
+	{`(database)s$`, "${1}"},
+	{`sis$`, "sis"},
+	{`(alias|atlas|bias|canvas|gas|lens)(es)?$`, "${1}"},
+	{`(stat|camp|bon|vir|b|cens|chor|corp|nex|prospect|syllab|surpl|consens|circ|apparat|radi|foc|gen|sin|lot|stimul)us(es)?$`, "${1}us"},
+	{`(menu|guru|haiku|tofu|tutu|bayou|caribou|sudoku)s$`, "${1}"},
+	{`^(emu|gnu|zebu)s$`, "${1}"},
+	{`us$`, "us"},
+	{`(x|ch|sh|ss|zz)es$`, "${1}"},
+	{`ss$`, "ss"},
+	{`(cook|mov|zomb|self|rook|goal|calor|brown|hood|smooth|pix)ies$`, "${1}ie"},
+	{`([^aeiouy]|qu)ies$`, "${1}y"},
+	{`s$`, ""},
+}
+
+var defaultIrregulars = [][2]string{
+	{"person", "people"},
+	{"man", "men"},
+	{"woman", "women"},
+	{"child", "children"},
+	{"tooth", "teeth"},
+	{"foot", "feet"},
+	{"goose", "geese"},
+	{"knife", "knives"},
+	{"wife", "wives"},
+	{"life", "lives"},
+	{"wolf", "wolves"},
+	{"half", "halves"},
+	{"shelf", "shelves"},
+	{"leaf", "leaves"},
+	{"loaf", "loaves"},
+	{"thief", "thieves"},
+	{"calf", "calves"},
+	{"self", "selves"},
+	{"elf", "elves"},
+	{"criterion", "criteria"},
+	{"phenomenon", "phenomena"},
+}
+
+var defaultUncountables = []string{
+	"equipment", "information", "news", "series", "species", "sheep", "fish",
+	"deer", "money", "rice", "police", "feedback", "software", "hardware",
+	"furniture", "luggage", "advice", "metadata",
+}
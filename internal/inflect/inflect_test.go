@@ -0,0 +1,58 @@
+package inflect
+
+import "testing"
+
+func TestRoundTrip(t *testing.T) {
+	tests := []struct {
+		singular, plural string
+	}{
+		{"category", "categories"},
+		{"person", "people"},
+		{"child", "children"},
+		{"status", "statuses"},
+		{"address", "addresses"},
+		{"bus", "buses"},
+		{"campus", "campuses"},
+		{"virus", "viruses"},
+		{"alias", "aliases"},
+		{"analysis", "analyses"},
+		{"crisis", "crises"},
+		{"thesis", "theses"},
+		{"box", "boxes"},
+		{"match", "matches"},
+		{"quiz", "quizzes"},
+		{"movie", "movies"},
+		{"key", "keys"},
+		{"mouse", "mice"},
+		{"knife", "knives"},
+		{"index", "indices"},
+		{"hero", "heroes"},
+		{"equipment", "equipment"},
+		{"news", "news"},
+
+		{"database", "databases"},
+		{"base", "bases"},
+		{"knowledge_base", "knowledge_bases"},
+		{"menu", "menus"},
+		{"sub_menu", "sub_menus"},
+		{"guru", "gurus"},
+		{"emu", "emus"},
+
+		{"order_item", "order_items"},
+		{"blog_post", "blog_posts"},
+		{"data_analysis", "data_analyses"},
+	}
+
+	r := New()
+	for _, tt := range tests {
+		if got := r.Pluralize(tt.singular); got != tt.plural {
+			t.Errorf("Pluralize(%q) = %q, want %q", tt.singular, got, tt.plural)
+		}
+		if got := r.Singularize(tt.plural); got != tt.singular {
+			t.Errorf("Singularize(%q) = %q, want %q", tt.plural, got, tt.singular)
+		}
+		if got := r.Singularize(tt.singular); got != tt.singular {
+			t.Errorf("Singularize(%q) = %q, want it unchanged", tt.singular, got)
+		}
+	}
+}
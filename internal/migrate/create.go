@@ -1,18 +1,50 @@
 package migrate
 
 import (
+	"errors"
 	"fmt"
+	"io/fs"
 	"os"
+	"strings"
 	"time"
 )
 
+// timestampLayout is the version prefix of migration filenames.
+const timestampLayout = "20060102150405"
+
+// Timestamp returns the version prefix for a new migration in dir: the
+// current time, or one second past the newest migration there if that is
+// not earlier, so migrations created within a second still apply in the
+// order they were created.
+func Timestamp(dir string) (string, error) {
+	next := time.Now().Truncate(time.Second)
+	entries, err := os.ReadDir(dir)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return "", fmt.Errorf("failed to read %s: %w", dir, err)
+	}
+	for _, e := range entries {
+		version, _, _ := strings.Cut(e.Name(), "_")
+		t, err := time.ParseInLocation(timestampLayout, version, time.Local)
+		if err != nil {
+			continue
+		}
+		if !next.After(t) {
+			next = t.Add(time.Second)
+		}
+	}
+	return next.Format(timestampLayout), nil
+}
+
 func Create(name string) error {
 	if name == "" {
 		return fmt.Errorf("missing migration name")
 	}
 
-	timestamp := time.Now().Format("20060102150405")
 	migrationDir := "db/migrations"
+	timestamp, err := Timestamp(migrationDir)
+	if err != nil {
+		return err
+	}
 
 	upFile := fmt.Sprintf("%s/%s_%s.up.sql", migrationDir, timestamp, name)
 	downFile := fmt.Sprintf("%s/%s_%s.down.sql", migrationDir, timestamp, name)
@@ -32,3 +64,55 @@ func Create(name string) error {
 	fmt.Printf("Migration created:\n  %s\n  %s\n", upFile, downFile)
 	return nil
 }
+
+// CreateGo writes a Go migration stub registering Up/Down funcs under the
+// same <timestamp>_<name> key the SQL migrations use.
+func CreateGo(name string) error {
+	if name == "" {
+		return fmt.Errorf("missing migration name")
+	}
+
+	migrationDir := "db/migrations"
+	timestamp, err := Timestamp(migrationDir)
+	if err != nil {
+		return err
+	}
+	key := fmt.Sprintf("%s_%s", timestamp, name)
+	goFile := fmt.Sprintf("%s/%s.go", migrationDir, key)
+
+	if err := os.MkdirAll(migrationDir, os.ModePerm); err != nil {
+		return fmt.Errorf("failed to create migrations directory: %w", err)
+	}
+
+	content := fmt.Sprintf(`package migrations
+
+import (
+	"github.com/Juksefantomet/gecho/migrate"
+	"gorm.io/gorm"
+)
+
+func init() {
+	migrate.Register("%[1]s", up%[2]s, down%[2]s)
+}
+
+// up%[2]s runs inside the transaction that records the migration.
+func up%[2]s(tx *gorm.DB) error {
+	// return tx.Exec("UPDATE ...").Error
+	return nil
+}
+
+// down%[2]s reverts up%[2]s. Pass nil to Register if it cannot be reverted.
+func down%[2]s(tx *gorm.DB) error {
+	return nil
+}
+`, key, timestamp)
+
+	if err := os.WriteFile(goFile, []byte(content), 0644); err != nil {
+		return fmt.Errorf("failed to create %s: %w", goFile, err)
+	}
+
+	fmt.Printf("Migration created:\n  %s\n", goFile)
+	fmt.Println("→ gecho migrate builds a runner with db/migrations linked in; run `go mod tidy` if it cannot find the gecho module.")
+	fmt.Println("→ To run it with MIGRATE_ON_START, import _ \"<module>/db/migrations\" in main.go.")
+	return nil
+}
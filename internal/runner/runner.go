@@ -0,0 +1,118 @@
+package runner
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// Dir is where the project-specific gecho binary is generated.
+const Dir = ".gecho/runner"
+
+// envVar marks the project runner so it never delegates to itself again.
+const envVar = "GECHO_RUNNER"
+
+// Needed reports whether any of dirs holds Go code (Go migrations or
+// seeds) that this gecho binary cannot run because it was not compiled in.
+func Needed(dirs ...string) bool {
+	if os.Getenv(envVar) != "" {
+		return false
+	}
+	for _, dir := range dirs {
+		files, _ := filepath.Glob(filepath.Join(dir, "*.go"))
+		for _, file := range files {
+			if !strings.HasSuffix(file, "_test.go") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Exec builds a gecho binary inside the project that links in the Go
+// packages in dirs, re-runs the current command line with it and exits
+// with its exit code. It only returns if the runner could not be started.
+func Exec(dirs ...string) error {
+	module, err := moduleName("go.mod")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(Dir, os.ModePerm); err != nil {
+		return fmt.Errorf("failed to create %s: %w", Dir, err)
+	}
+	if err := os.WriteFile(filepath.Join(Dir, ".gitignore"), []byte("*\n"), 0644); err != nil {
+		return fmt.Errorf("failed to write %s/.gitignore: %w", Dir, err)
+	}
+	if err := os.WriteFile(filepath.Join(Dir, "main.go"), []byte(mainSource(module, dirs)), 0644); err != nil {
+		return fmt.Errorf("failed to write %s/main.go: %w", Dir, err)
+	}
+
+	bin := filepath.Join(Dir, "gecho")
+	build := exec.Command("go", "build", "-o", bin, "./"+Dir)
+	build.Stdout = os.Stderr
+	build.Stderr = os.Stderr
+	if err := build.Run(); err != nil {
+		return fmt.Errorf("failed to build %s for Go migrations and seeds "+
+			"(is github.com/Juksefantomet/gecho in go.mod? try `go mod tidy`): %w", Dir, err)
+	}
+
+	run := exec.Command(bin, os.Args[1:]...)
+	run.Stdin = os.Stdin
+	run.Stdout = os.Stdout
+	run.Stderr = os.Stderr
+	run.Env = append(os.Environ(), envVar+"=1")
+
+	err = run.Run()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		os.Exit(exitErr.ExitCode())
+	}
+	if err != nil {
+		return fmt.Errorf("failed to run %s: %w", bin, err)
+	}
+	os.Exit(0)
+	return nil
+}
+
+func mainSource(module string, dirs []string) string {
+	var imports strings.Builder
+	for _, dir := range dirs {
+		if Needed(dir) {
+			fmt.Fprintf(&imports, "\t_ %q\n", module+"/"+filepath.ToSlash(dir))
+		}
+	}
+
+	return fmt.Sprintf(`// Code generated by gecho. DO NOT EDIT.
+//
+// This is the gecho CLI with the project's Go migrations and seeds linked in.
+package main
+
+import (
+	"github.com/Juksefantomet/gecho/cmd"
+
+%s)
+
+func main() {
+	cmd.Execute()
+}
+`, imports.String())
+}
+
+func moduleName(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "module ") {
+			return strings.TrimSpace(strings.TrimPrefix(line, "module")), nil
+		}
+	}
+	return "", fmt.Errorf("no module declaration found in %s", path)
+}
@@ -0,0 +1,236 @@
+package scaffold
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/Juksefantomet/gecho/internal/inflect"
+)
+
+// DestroyOptions configures Destroy.
+type DestroyOptions struct {
+	// Force removes files edited since they were generated, migrations
+	// that were already applied, and resources other resources reference.
+	Force bool
+	// Applied reports whether a migration, given by its .up.sql file name,
+	// is recorded in the migrations table. nil skips the check.
+	Applied func(name string) (bool, error)
+}
+
+// Destroy reverses Run: it removes the files the scaffold of rawName
+// generated, as long as they are unmodified, and its routes in main.go.
+func Destroy(rawName string, opts DestroyOptions) error {
+	if rawName == "" {
+		return fmt.Errorf("missing model name")
+	}
+	moduleName := getModuleName("go.mod")
+	inflections, err := inflect.Load()
+	if err != nil {
+		return err
+	}
+	res, err := newResource(rawName, inflections)
+	if err != nil {
+		return err
+	}
+
+	m, err := loadManifest(res.snakeName)
+	if errors.Is(err, fs.ErrNotExist) {
+		m, err = guessManifest(res)
+	}
+	if err != nil {
+		return err
+	}
+
+	dependents, err := referencedBy(res, m)
+	if err != nil {
+		return err
+	}
+	if len(dependents) > 0 {
+		if !opts.Force {
+			return fmt.Errorf("%s is referenced by %s; destroy those first, or use --force",
+				res.snakeName, strings.Join(dependents, "; "))
+		}
+		fmt.Printf("⚠ Destroying %s although it is referenced by %s; those will no longer build or migrate.\n",
+			res.snakeName, strings.Join(dependents, "; "))
+	}
+
+	applied, err := appliedMigrations(m, opts.Applied)
+	if err != nil {
+		fmt.Printf("⚠ Could not check which migrations are applied: %v\n", err)
+	}
+	unknown := err != nil
+
+	var removed, kept []string
+	for _, path := range m.paths() {
+		data, err := os.ReadFile(path)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", path, err)
+		}
+
+		if applied[path] {
+			if !opts.Force {
+				fmt.Printf("⚠ Keeping %s: its migration is recorded in the migrations table. "+
+					"Roll it back with `gecho migrate down` first, or use --force.\n", path)
+				kept = append(kept, path)
+				continue
+			}
+			fmt.Printf("⚠ Removing %s although its migration is applied; "+
+				"`gecho migrate status` will list it as orphaned.\n", path)
+		}
+		if unknown && isMigration(path) {
+			if !opts.Force {
+				fmt.Printf("⚠ Keeping %s: its migration may be applied. "+
+					"Check with `gecho migrate status`, or use --force.\n", path)
+				kept = append(kept, path)
+				continue
+			}
+			fmt.Printf("⚠ Removing %s although its migration may be applied.\n", path)
+		}
+
+		if sum := m.Files[path]; sum != checksum(data) && !opts.Force {
+			reason := "modified since it was generated"
+			if sum == "" {
+				reason = "not recorded as generated by gecho scaffold"
+			}
+			fmt.Printf("⚠ Keeping %s: %s (use --force to remove it).\n", path, reason)
+			kept = append(kept, path)
+			continue
+		}
+
+		if err := os.Remove(path); err != nil {
+			return fmt.Errorf("failed to remove %s: %w", path, err)
+		}
+		removed = append(removed, path)
+	}
+
+	if len(kept) == 0 {
+		if err := os.Remove(manifestPath(res.snakeName)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("failed to remove %s: %w", manifestPath(res.snakeName), err)
+		}
+	}
+
+	if len(removed) == 0 {
+		fmt.Println("No files removed.")
+	} else {
+		fmt.Printf("Removed:\n  %s\n", strings.Join(removed, "\n  "))
+	}
+
+	dropped, err := unregisterRoutes(mainFile, moduleName, mergeRoutes(res.routes(nil), m.Routes))
+	if err != nil {
+		fmt.Printf("⚠ Could not remove routes from %s (%v). Remove them by hand.\n", mainFile, err)
+	} else if len(dropped) > 0 {
+		fmt.Printf("Removed %d route(s) from %s.\n", len(dropped), mainFile)
+	}
+	return nil
+}
+
+// guessManifest stands in for the manifest of resources scaffolded before
+// manifests were written. Without checksums every file counts as modified.
+func guessManifest(res resource) (*manifest, error) {
+	m := newManifest(res.snakeName)
+	for _, pattern := range []string{"_" + res.snakeName + ".up.sql", "_" + res.snakeName + ".down.sql"} {
+		matches, err := filepath.Glob(filepath.Join(migrationDir, "*"+pattern))
+		if err != nil {
+			return nil, err
+		}
+		for _, path := range matches {
+			m.Files[filepath.ToSlash(path)] = ""
+		}
+	}
+	for _, path := range []string{res.modelFile, res.routeFile, res.queriesFile} {
+		m.Files[path] = ""
+	}
+	return m, nil
+}
+
+// appliedMigrations returns the migration files in m whose migration is
+// recorded as applied, .down.sql files included. An error means it is
+// unknown which are.
+func appliedMigrations(m *manifest, isApplied func(name string) (bool, error)) (map[string]bool, error) {
+	applied := make(map[string]bool)
+	if isApplied == nil {
+		return applied, nil
+	}
+	for _, path := range m.paths() {
+		if !strings.HasSuffix(path, ".up.sql") {
+			continue
+		}
+		if _, err := os.Stat(path); err != nil {
+			continue
+		}
+		ok, err := isApplied(filepath.Base(path))
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			applied[path] = true
+			applied[strings.TrimSuffix(path, ".up.sql")+".down.sql"] = true
+		}
+	}
+	return applied, nil
+}
+
+// isMigration reports whether path is a migration file.
+func isMigration(path string) bool {
+	return strings.HasSuffix(path, ".up.sql") || strings.HasSuffix(path, ".down.sql")
+}
+
+// referencedBy returns the resources whose model or migration refers to res
+// through a references field, with the files that do, e.g.
+// "comment (app/models/comment.go)". Files of res itself are skipped.
+func referencedBy(res resource, m *manifest) ([]string, error) {
+	association := regexp.MustCompile(`\*` + regexp.QuoteMeta(res.structName) + "\\s+`[^`]*foreignKey:")
+	foreignKey := regexp.MustCompile(`(?i)\bREFERENCES\s+"?` + regexp.QuoteMeta(res.pluralName) + `"?\s*\(`)
+	migrationVersion := regexp.MustCompile(`^\d+_`)
+
+	files := make(map[string][]string) // resource → files referring to res
+	check := func(pattern string, re *regexp.Regexp, name func(base string) string) error {
+		paths, err := filepath.Glob(pattern)
+		if err != nil {
+			return err
+		}
+		for _, path := range paths {
+			path = filepath.ToSlash(path)
+			if _, own := m.Files[path]; own || path == res.modelFile {
+				continue
+			}
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return fmt.Errorf("failed to read %s: %w", path, err)
+			}
+			if re.Match(data) {
+				dependent := name(filepath.Base(path))
+				files[dependent] = append(files[dependent], path)
+			}
+		}
+		return nil
+	}
+	err := check(filepath.Join(modelsDir, "*.go"), association, func(base string) string {
+		return toSnakeCase(strings.TrimSuffix(base, ".go"))
+	})
+	if err != nil {
+		return nil, err
+	}
+	err = check(filepath.Join(migrationDir, "*.up.sql"), foreignKey, func(base string) string {
+		return migrationVersion.ReplaceAllString(strings.TrimSuffix(base, ".up.sql"), "")
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var dependents []string
+	for name, paths := range files {
+		dependents = append(dependents, fmt.Sprintf("%s (%s)", name, strings.Join(paths, ", ")))
+	}
+	sort.Strings(dependents)
+	return dependents, nil
+}
@@ -0,0 +1,355 @@
+package scaffold
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/Juksefantomet/gecho/internal/inflect"
+)
+
+// Field is a column of a scaffolded resource, parsed from a
+// name:type[:modifier...] argument such as price:decimal:index.
+type Field struct {
+	Name    string // snake_case column name
+	Type    string // one of fieldTypes
+	Unique  bool
+	Index   bool
+	Null    bool
+	Default string // SQL default expression, "" for none
+
+	// Set for references fields: post:references has the column post_id
+	// referencing posts(id).
+	References string // referenced resource, e.g. post
+	RefTable   string // its table, e.g. posts
+	OnDelete   string // ON DELETE action, e.g. CASCADE; "" for the default
+}
+
+// fieldType describes how a scaffold field type maps to Postgres, Go and
+// Swagger.
+type fieldType struct {
+	sql     string
+	goType  string
+	format  string // Swagger format, e.g. date-time
+	swagger string // swaggertype override for types swag cannot infer
+	quoted  bool   // defaults are string literals
+	model   bool   // goType is declared in the models package
+}
+
+var fieldTypes = map[string]fieldType{
+	"string":  {sql: "VARCHAR(255)", goType: "string", quoted: true},
+	"text":    {sql: "TEXT", goType: "string", quoted: true},
+	"int":     {sql: "INTEGER", goType: "int"},
+	"bigint":  {sql: "BIGINT", goType: "int64"},
+	"bool":    {sql: "BOOLEAN", goType: "bool"},
+	"decimal": {sql: "NUMERIC(12,2)", goType: "float64"},
+	"time":    {sql: "TIMESTAMPTZ", goType: "time.Time", format: "date-time", quoted: true},
+	"date":    {sql: "DATE", goType: "Date", format: "date", swagger: "string", quoted: true, model: true},
+	"uuid":    {sql: "UUID", goType: "string", format: "uuid", quoted: true},
+	"jsonb":   {sql: "JSONB", goType: "json.RawMessage", swagger: "object", quoted: true},
+
+	"references": {sql: "INTEGER", goType: "int"},
+}
+
+// typeAliases are accepted in place of a type.
+var typeAliases = map[string]string{"belongs_to": "references"}
+
+// onDeleteActions maps the on_delete= values of references fields to SQL.
+var onDeleteActions = map[string]string{
+	"cascade":  "CASCADE",
+	"restrict": "RESTRICT",
+	"set_null": "SET NULL",
+}
+
+// defaultFields are used when scaffold is given no fields, matching the
+// table gecho has always generated.
+var defaultFields = []Field{{Name: "name", Type: "text", Unique: true}}
+
+// reservedColumns are generated for every resource.
+var reservedColumns = map[string]bool{"id": true, "created_at": true, "updated_at": true}
+
+var fieldName = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+
+// ParseFields parses name:type[:modifier...] arguments. Modifiers are
+// unique, index, null and default=<value>, plus on_delete=<action> for
+// references, whose tables are named with inflections. A default value
+// runs to the end of the argument, so it may contain colons.
+func ParseFields(args []string, inflections *inflect.Rules) ([]Field, error) {
+	if len(args) == 0 {
+		return defaultFields, nil
+	}
+
+	var fields []Field
+	seen := make(map[string]bool)
+	for _, arg := range args {
+		f, err := parseField(arg, inflections)
+		if err != nil {
+			return nil, err
+		}
+		if seen[f.Name] {
+			return nil, fmt.Errorf("field %s given twice", f.Name)
+		}
+		seen[f.Name] = true
+		fields = append(fields, f)
+	}
+	return fields, nil
+}
+
+func parseField(arg string, inflections *inflect.Rules) (Field, error) {
+	parts := strings.SplitN(arg, ":", 3)
+	if len(parts) < 2 {
+		return Field{}, fmt.Errorf("invalid field %q, expected name:type[:modifier]", arg)
+	}
+
+	f := Field{Name: toSnakeCase(parts[0]), Type: strings.ToLower(parts[1])}
+	if alias, ok := typeAliases[f.Type]; ok {
+		f.Type = alias
+	}
+	if f.Type == "references" {
+		f.References = inflections.Singularize(strings.TrimSuffix(f.Name, "_id"))
+		f.RefTable = inflections.Pluralize(f.References)
+		f.Name = f.References + "_id"
+		f.Index = true
+	}
+	if !fieldName.MatchString(f.Name) {
+		return Field{}, fmt.Errorf("invalid field name %q", parts[0])
+	}
+	if reservedColumns[f.Name] {
+		return Field{}, fmt.Errorf("field %s is generated for every resource", f.Name)
+	}
+	if reservedSQL[f.Name] {
+		return Field{}, fmt.Errorf("field %s is a reserved word in Postgres", f.Name)
+	}
+	t, ok := fieldTypes[f.Type]
+	if !ok {
+		return Field{}, fmt.Errorf("unknown type %q for field %s (supported: %s)", parts[1], f.Name, supportedTypes())
+	}
+
+	var mods []string
+	if len(parts) == 3 {
+		mods = splitModifiers(parts[2])
+	}
+	for _, mod := range mods {
+		switch {
+		case mod == "unique":
+			f.Unique = true
+		case mod == "index":
+			f.Index = true
+		case mod == "null":
+			f.Null = true
+		case strings.HasPrefix(mod, "on_delete=") && f.References != "":
+			action, ok := onDeleteActions[strings.TrimPrefix(mod, "on_delete=")]
+			if !ok {
+				return Field{}, fmt.Errorf("unknown %s for field %s (supported: cascade, restrict, set_null)", mod, f.Name)
+			}
+			f.OnDelete = action
+		case strings.HasPrefix(mod, "default="):
+			def, err := defaultExpr(t, strings.TrimPrefix(mod, "default="))
+			if err != nil {
+				return Field{}, fmt.Errorf("field %s: %w", f.Name, err)
+			}
+			f.Default = def
+		default:
+			return Field{}, fmt.Errorf("unknown modifier %q for field %s (supported: unique, index, null, default=<value>, "+
+				"on_delete=<action> for references)", mod, f.Name)
+		}
+	}
+	if f.OnDelete == "SET NULL" && !f.Null {
+		return Field{}, fmt.Errorf("field %s: on_delete=set_null needs the null modifier", f.Name)
+	}
+	return f, nil
+}
+
+// splitModifiers splits the modifiers of a field on colons, except within
+// a default= value, which takes the rest: unique:default=12:00 gives unique
+// and default=12:00.
+func splitModifiers(s string) []string {
+	var mods []string
+	for s != "" {
+		if strings.HasPrefix(s, "default=") {
+			return append(mods, s)
+		}
+		mod, rest, _ := strings.Cut(s, ":")
+		mods = append(mods, mod)
+		s = rest
+	}
+	return mods
+}
+
+// functionCall matches a default that calls a function, e.g. now() or
+// gen_random_uuid(), which is used as SQL rather than quoted.
+var functionCall = regexp.MustCompile(`(?i)^[a-z_][a-z0-9_]*\(.*\)$`)
+
+// decimalLiteral matches the numeric defaults Postgres takes unquoted.
+var decimalLiteral = regexp.MustCompile(`^[+-]?(\d+(\.\d*)?|\.\d+)$`)
+
+// defaultExpr turns the value of a default= modifier into SQL. Values of
+// text-like types are quoted unless they call a function, e.g. now().
+func defaultExpr(t fieldType, value string) (string, error) {
+	if value == "" {
+		return "", fmt.Errorf("empty default")
+	}
+	if functionCall.MatchString(value) {
+		return value, nil
+	}
+	switch {
+	case t.quoted:
+		return "'" + strings.ReplaceAll(value, "'", "''") + "'", nil
+	case t.goType == "bool":
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return "", fmt.Errorf("invalid boolean default %q", value)
+		}
+		return strings.ToUpper(strconv.FormatBool(b)), nil
+	case t.goType == "int" || t.goType == "int64":
+		if _, err := strconv.ParseInt(value, 10, 64); err != nil {
+			return "", fmt.Errorf("invalid integer default %q", value)
+		}
+		return value, nil
+	default:
+		if !decimalLiteral.MatchString(value) {
+			return "", fmt.Errorf("invalid numeric default %q", value)
+		}
+		return value, nil
+	}
+}
+
+func supportedTypes() string {
+	return "string, text, int, bigint, bool, decimal, time, date, uuid, jsonb, references"
+}
+
+// Column returns the column definition used in the up migration, with the
+// name padded to width so the table's types line up.
+func (f Field) Column(width int) string {
+	def := fmt.Sprintf("%-*s %s", width, f.Name, fieldTypes[f.Type].sql)
+	if !f.Null {
+		def += " NOT NULL"
+	}
+	if f.Unique {
+		def += " UNIQUE"
+	}
+	if f.Default != "" {
+		def += " DEFAULT " + f.Default
+	}
+	if f.References != "" {
+		def += " REFERENCES " + f.RefTable + "(id)"
+		if f.OnDelete != "" {
+			def += " ON DELETE " + f.OnDelete
+		}
+	}
+	return def
+}
+
+// GoName returns the struct field name.
+func (f Field) GoName() string {
+	return toPascalCase(f.Name)
+}
+
+// GoType returns the struct field type in the models package, a pointer for
+// nullable columns and columns with a default, where nil leaves the value to
+// the database.
+func (f Field) GoType() string {
+	if f.Pointer() {
+		return "*" + fieldTypes[f.Type].goType
+	}
+	return fieldTypes[f.Type].goType
+}
+
+// RequestType returns the field type in create and replace requests, which
+// is GoType as seen from outside the models package.
+func (f Field) RequestType() string {
+	if f.Pointer() {
+		return "*" + f.qualifiedType()
+	}
+	return f.qualifiedType()
+}
+
+// qualifiedType returns the Go type of f, qualified with models. when it is
+// declared there, e.g. models.Date.
+func (f Field) qualifiedType() string {
+	t := fieldTypes[f.Type]
+	if t.model {
+		return "models." + t.goType
+	}
+	return t.goType
+}
+
+// Pointer reports whether GoType is a pointer. jsonb fields are
+// json.RawMessage either way, which is nil when omitted.
+func (f Field) Pointer() bool {
+	return (f.Null || f.Default != "") && f.Type != "jsonb"
+}
+
+// RefStruct returns the model type of a references field, e.g. Post.
+func (f Field) RefStruct() string {
+	return toPascalCase(f.References)
+}
+
+// RefVar returns a Go variable name for the referenced ID, e.g. postID.
+func (f Field) RefVar() string {
+	return toLowerCamelCase(f.Name)
+}
+
+// PatchType returns the field type in partial update requests, where nil
+// means unchanged.
+func (f Field) PatchType() string {
+	if f.Type == "jsonb" {
+		return f.qualifiedType()
+	}
+	return "*" + f.qualifiedType()
+}
+
+// Required reports whether requests must give the field a non-empty value:
+// text-like columns that are NOT NULL without a default.
+func (f Field) Required() bool {
+	goType := fieldTypes[f.Type].goType
+	return goType == "string" && !f.Null && f.Default == ""
+}
+
+// Tags returns the struct tags of the model field: json, gorm and the
+// Swagger hints swag cannot derive from the Go type. Columns with a default
+// are tagged default:(-), so gorm leaves them out of the insert while nil
+// and reads back what the database filled in; the expression itself stays
+// in the migration.
+func (f Field) Tags() string {
+	gorm := []string{"column:" + f.Name}
+	if !f.Null {
+		gorm = append(gorm, "not null")
+	}
+	if f.Unique {
+		gorm = append(gorm, "unique")
+	}
+	if f.Index {
+		gorm = append(gorm, "index")
+	}
+	if f.Default != "" {
+		gorm = append(gorm, "default:(-)")
+	}
+
+	tags := fmt.Sprintf(`json:"%s" gorm:"%s"`, f.Name, strings.Join(gorm, ";")) + f.swaggerTags()
+	if f.Null {
+		tags += ` extensions:"x-nullable"`
+	}
+	return tags
+}
+
+// RequestTags returns the struct tags of the request field: json and the
+// Swagger hints of Tags, so both document the same type.
+func (f Field) RequestTags() string {
+	return fmt.Sprintf(`json:"%s"`, f.Name) + f.swaggerTags()
+}
+
+// swaggerTags returns the swaggertype and format tags of f, each preceded by
+// a space.
+func (f Field) swaggerTags() string {
+	t := fieldTypes[f.Type]
+	var tags string
+	if t.swagger != "" {
+		tags += fmt.Sprintf(` swaggertype:"%s"`, t.swagger)
+	}
+	if t.format != "" {
+		tags += fmt.Sprintf(` format:"%s"`, t.format)
+	}
+	return tags
+}
@@ -0,0 +1,70 @@
+package scaffold
+
+import (
+	"testing"
+
+	"github.com/Juksefantomet/gecho/internal/inflect"
+)
+
+func TestParseFields(t *testing.T) {
+	tests := []struct {
+		arg    string
+		column string // Column(0), "" if arg is rejected
+	}{
+		{"title:string", "title VARCHAR(255) NOT NULL"},
+		{"body:text:null", "body TEXT"},
+		{"sku:string:unique:index", "sku VARCHAR(255) NOT NULL UNIQUE"},
+		{"Price:decimal", "price NUMERIC(12,2) NOT NULL"},
+		{"post:references", "post_id INTEGER NOT NULL REFERENCES posts(id)"},
+		{"post_id:belongs_to:null:on_delete=set_null", "post_id INTEGER REFERENCES posts(id) ON DELETE SET NULL"},
+		{"category:references:on_delete=cascade", "category_id INTEGER NOT NULL REFERENCES categories(id) ON DELETE CASCADE"},
+
+		{"flag:bool:default=true", "flag BOOLEAN NOT NULL DEFAULT TRUE"},
+		{"flag:bool:default=1", "flag BOOLEAN NOT NULL DEFAULT TRUE"},
+		{"flag:bool:default=t", "flag BOOLEAN NOT NULL DEFAULT TRUE"},
+		{"flag:bool:default=F", "flag BOOLEAN NOT NULL DEFAULT FALSE"},
+		{"flag:bool:default=yes", ""},
+		{"n:int:default=-3", "n INTEGER NOT NULL DEFAULT -3"},
+		{"n:int:default=1.5", ""},
+		{"n:bigint:default=1e3", ""},
+		{"price:decimal:default=9.99", "price NUMERIC(12,2) NOT NULL DEFAULT 9.99"},
+		{"price:decimal:default=NaN", ""},
+		{"price:decimal:default=1e3", ""},
+		{"note:string:default=it's", "note VARCHAR(255) NOT NULL DEFAULT 'it''s'"},
+		{"note:string:default=see (below)", "note VARCHAR(255) NOT NULL DEFAULT 'see (below)'"},
+		{"opens:string:default=12:00", "opens VARCHAR(255) NOT NULL DEFAULT '12:00'"},
+		{"url:string:unique:default=https://example.com:8080/x", "url VARCHAR(255) NOT NULL UNIQUE DEFAULT 'https://example.com:8080/x'"},
+		{"starts_at:time:default=now()", "starts_at TIMESTAMPTZ NOT NULL DEFAULT now()"},
+		{"uid:uuid:default=gen_random_uuid()", "uid UUID NOT NULL DEFAULT gen_random_uuid()"},
+		{"held_on:date:default=2024-05-01", "held_on DATE NOT NULL DEFAULT '2024-05-01'"},
+		{"n:int:default=", ""},
+
+		{"title", ""},
+		{"title:blob", ""},
+		{"title:string:sorted", ""},
+		{"order:string", ""},
+		{"id:int", ""},
+		{"1st:string", ""},
+		{"body:text:on_delete=cascade", ""},
+		{"post:references:on_delete=set_null", ""},
+	}
+
+	inflections := inflect.New()
+	for _, tt := range tests {
+		fields, err := ParseFields([]string{tt.arg}, inflections)
+		switch {
+		case tt.column == "" && err == nil:
+			t.Errorf("ParseFields(%q) = %q, want an error", tt.arg, fields[0].Column(0))
+		case tt.column != "" && err != nil:
+			t.Errorf("ParseFields(%q): %v", tt.arg, err)
+		case tt.column != "" && fields[0].Column(0) != tt.column:
+			t.Errorf("ParseFields(%q) = %q, want %q", tt.arg, fields[0].Column(0), tt.column)
+		}
+	}
+}
+
+func TestParseFieldsTwice(t *testing.T) {
+	if _, err := ParseFields([]string{"title:string", "Title:text"}, inflect.New()); err == nil {
+		t.Error("ParseFields accepted the same field twice")
+	}
+}
@@ -0,0 +1,111 @@
+package scaffold
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// manifestDir holds one manifest per scaffolded resource. It is meant to be
+// committed so anyone can destroy a scaffold later.
+const manifestDir = ".gecho/scaffolds"
+
+// manifest records the files a scaffold wrote and the SHA-256 of their
+// generated content, so destroy can tell whether they were edited since,
+// and the routes it registered.
+type manifest struct {
+	Name   string            `json:"name"`
+	Files  map[string]string `json:"files"`
+	Routes []route           `json:"routes,omitempty"`
+}
+
+func newManifest(name string) *manifest {
+	return &manifest{Name: name, Files: make(map[string]string)}
+}
+
+func manifestPath(name string) string {
+	return filepath.Join(manifestDir, name+".json")
+}
+
+// add records that path was generated with content.
+func (m *manifest) add(path, content string) {
+	m.Files[path] = checksum([]byte(content))
+}
+
+// save merges the manifest into the one of an earlier scaffold of the same
+// name, if any, and writes it.
+func (m *manifest) save() error {
+	if previous, err := loadManifest(m.Name); err == nil {
+		for path, sum := range previous.Files {
+			if _, ok := m.Files[path]; !ok {
+				m.Files[path] = sum
+			}
+		}
+		m.Routes = mergeRoutes(previous.Routes, m.Routes)
+	}
+
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(manifestDir, os.ModePerm); err != nil {
+		return fmt.Errorf("failed to create %s: %w", manifestDir, err)
+	}
+	path := manifestPath(m.Name)
+	if err := os.WriteFile(path, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return nil
+}
+
+func (m *manifest) paths() []string {
+	paths := make([]string, 0, len(m.Files))
+	for path := range m.Files {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// loadManifest reads the manifest of the named scaffold, returning an
+// fs.ErrNotExist error if it was never scaffolded with one.
+func loadManifest(name string) (*manifest, error) {
+	data, err := os.ReadFile(manifestPath(name))
+	if err != nil {
+		return nil, err
+	}
+	m := newManifest(name)
+	if err := json.Unmarshal(data, m); err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", manifestPath(name), err)
+	}
+	if m.Files == nil {
+		return nil, errors.New("no files recorded in " + manifestPath(name))
+	}
+	return m, nil
+}
+
+// mergeRoutes returns a followed by the routes of b whose method and path
+// are not in a.
+func mergeRoutes(a, b []route) []route {
+	seen := make(map[string]bool, len(a))
+	for _, r := range a {
+		seen[r.key()] = true
+	}
+	for _, r := range b {
+		if !seen[r.key()] {
+			seen[r.key()] = true
+			a = append(a, r)
+		}
+	}
+	return a
+}
+
+func checksum(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,175 @@
+package scaffold
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// initialisms are written in one case in Go names, as golint expects:
+// APIKey, UserID, HTTPLog.
+var initialisms = map[string]bool{
+	"acl": true, "api": true, "ascii": true, "cpu": true, "css": true, "dns": true,
+	"eof": true, "guid": true, "html": true, "http": true, "https": true, "id": true,
+	"ip": true, "json": true, "lhs": true, "qps": true, "ram": true, "rhs": true,
+	"rpc": true, "sla": true, "smtp": true, "sql": true, "ssh": true, "tcp": true,
+	"tls": true, "ttl": true, "udp": true, "ui": true, "uid": true, "uuid": true,
+	"uri": true, "url": true, "utf8": true, "vm": true, "xml": true, "xmpp": true,
+	"xsrf": true, "xss": true,
+}
+
+// mixedInitialisms are initialisms written in mixed case, keyed by their
+// lowercase word: IPv6Address.
+var mixedInitialisms = map[string]string{"ipv4": "IPv4", "ipv6": "IPv6"}
+
+// reservedSQL are the Postgres key words that cannot name a table or
+// column without quoting.
+var reservedSQL = map[string]bool{
+	"all": true, "analyse": true, "analyze": true, "and": true, "any": true,
+	"array": true, "as": true, "asc": true, "asymmetric": true, "authorization": true,
+	"binary": true, "both": true, "case": true, "cast": true, "check": true,
+	"collate": true, "collation": true, "column": true, "concurrently": true,
+	"constraint": true, "create": true, "cross": true, "current_catalog": true,
+	"current_date": true, "current_role": true, "current_schema": true,
+	"current_time": true, "current_timestamp": true, "current_user": true,
+	"default": true, "deferrable": true, "desc": true, "distinct": true, "do": true,
+	"else": true, "end": true, "except": true, "false": true, "fetch": true,
+	"for": true, "foreign": true, "freeze": true, "from": true, "full": true,
+	"grant": true, "group": true, "having": true, "ilike": true, "in": true,
+	"initially": true, "inner": true, "intersect": true, "into": true, "is": true,
+	"isnull": true, "join": true, "lateral": true, "leading": true, "left": true,
+	"like": true, "limit": true, "localtime": true, "localtimestamp": true,
+	"natural": true, "not": true, "notnull": true, "null": true, "offset": true,
+	"on": true, "only": true, "or": true, "order": true, "outer": true,
+	"overlaps": true, "placing": true, "primary": true, "references": true,
+	"returning": true, "right": true, "select": true, "session_user": true,
+	"similar": true, "some": true, "symmetric": true, "system_user": true,
+	"table": true, "tablesample": true, "then": true, "to": true, "trailing": true,
+	"true": true, "union": true, "unique": true, "user": true, "using": true,
+	"variadic": true, "verbose": true, "when": true, "where": true, "window": true,
+	"with": true,
+}
+
+// splitWords splits a name in any of the forms scaffold accepts
+// (PascalCase, camelCase, snake_case, kebab-case or spaced) into lowercase
+// words. An acronym ends before the capital that starts the next word, so
+// HTTPLog is http, log, but keeps a plural s: URLs is urls, UserIDs is
+// user, ids. Mixed-case initialisms stay whole: IPv6Address is ipv6,
+// address.
+func splitWords(s string) []string {
+	var (
+		words []string
+		word  []rune
+	)
+	flush := func() {
+		if len(word) > 0 {
+			words = append(words, strings.ToLower(string(word)))
+			word = word[:0]
+		}
+	}
+
+	runes := []rune(s)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		if r == '_' || r == '-' || r == '.' || unicode.IsSpace(r) {
+			flush()
+			continue
+		}
+		if m := mixedInitialismAt(runes[i:]); m != "" {
+			flush()
+			word = append(word, []rune(m)...)
+			i += len(m) - 1
+			continue
+		}
+		if unicode.IsUpper(r) && i > 0 {
+			prev := runes[i-1]
+			nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			pluralS := nextLower && runes[i+1] == 's' && endsWord(runes, i+1)
+			if unicode.IsLower(prev) || unicode.IsDigit(prev) || (unicode.IsUpper(prev) && nextLower && !pluralS) {
+				flush()
+			}
+		}
+		word = append(word, r)
+	}
+	flush()
+	return words
+}
+
+// mixedInitialismAt returns the mixed-case initialism runes starts with, as
+// written there, or "" if there is none.
+func mixedInitialismAt(runes []rune) string {
+	for _, m := range mixedInitialisms {
+		n := len([]rune(m))
+		if len(runes) >= n && string(runes[:n]) == m && endsWord(runes, n) {
+			return m
+		}
+	}
+	return ""
+}
+
+// endsWord reports whether a word may end before runes[i], allowing for a
+// plural s: at the end of the name, or before an uppercase letter, digit or
+// separator.
+func endsWord(runes []rune, i int) bool {
+	if i < len(runes) && runes[i] == 's' {
+		i++
+	}
+	return i == len(runes) || !unicode.IsLower(runes[i])
+}
+
+// checkWords rejects words that cannot form Go and SQL identifiers.
+func checkWords(raw string, words []string) error {
+	if len(words) == 0 {
+		return fmt.Errorf("invalid name %q", raw)
+	}
+	for i, w := range words {
+		for j, r := range w {
+			if r > unicode.MaxASCII || !(unicode.IsLetter(r) || unicode.IsDigit(r)) || (i == 0 && j == 0 && !unicode.IsLetter(r)) {
+				return fmt.Errorf("invalid name %q: use letters and digits, starting with a letter", raw)
+			}
+		}
+	}
+	return nil
+}
+
+func toSnakeCase(s string) string {
+	return strings.Join(splitWords(s), "_")
+}
+
+func toPascalCase(s string) string {
+	var b strings.Builder
+	for _, w := range splitWords(s) {
+		b.WriteString(goWord(w))
+	}
+	return b.String()
+}
+
+// goWord capitalizes a lowercase word for a Go name, writing initialisms
+// and their plurals as Go does: ID, IDs, IPv6.
+func goWord(w string) string {
+	if initialisms[w] {
+		return strings.ToUpper(w)
+	}
+	if m, ok := mixedInitialisms[w]; ok {
+		return m
+	}
+	if base, ok := strings.CutSuffix(w, "s"); ok {
+		if initialisms[base] {
+			return strings.ToUpper(base) + "s"
+		}
+		if m, ok := mixedInitialisms[base]; ok {
+			return m + "s"
+		}
+	}
+	return strings.ToUpper(w[:1]) + w[1:]
+}
+
+// toLowerCamelCase is toPascalCase with the first word in lower case:
+// apiKey, userID.
+func toLowerCamelCase(s string) string {
+	words := splitWords(s)
+	if len(words) == 0 {
+		return ""
+	}
+	return words[0] + toPascalCase(strings.Join(words[1:], "_"))
+}
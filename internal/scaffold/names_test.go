@@ -0,0 +1,74 @@
+package scaffold
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/Juksefantomet/gecho/internal/inflect"
+)
+
+func TestNames(t *testing.T) {
+	tests := []struct {
+		in                   string
+		words                []string
+		snake, pascal, camel string
+	}{
+		{"BlogPost", []string{"blog", "post"}, "blog_post", "BlogPost", "blogPost"},
+		{"userProfile", []string{"user", "profile"}, "user_profile", "UserProfile", "userProfile"},
+		{"api-key", []string{"api", "key"}, "api_key", "APIKey", "apiKey"},
+		{"http log", []string{"http", "log"}, "http_log", "HTTPLog", "httpLog"},
+		{"HTTPLog", []string{"http", "log"}, "http_log", "HTTPLog", "httpLog"},
+		{"UserID", []string{"user", "id"}, "user_id", "UserID", "userID"},
+		{"user_id", []string{"user", "id"}, "user_id", "UserID", "userID"},
+		{"url", []string{"url"}, "url", "URL", "url"},
+
+		{"URLs", []string{"urls"}, "urls", "URLs", "urls"},
+		{"urls", []string{"urls"}, "urls", "URLs", "urls"},
+		{"UserIDs", []string{"user", "ids"}, "user_ids", "UserIDs", "userIDs"},
+		{"user_ids", []string{"user", "ids"}, "user_ids", "UserIDs", "userIDs"},
+		{"APIsList", []string{"apis", "list"}, "apis_list", "APIsList", "apisList"},
+		{"IPv6Address", []string{"ipv6", "address"}, "ipv6_address", "IPv6Address", "ipv6Address"},
+		{"ipv6_addresses", []string{"ipv6", "addresses"}, "ipv6_addresses", "IPv6Addresses", "ipv6Addresses"},
+		{"ServerIPv4s", []string{"server", "ipv4s"}, "server_ipv4s", "ServerIPv4s", "serverIPv4s"},
+	}
+
+	for _, tt := range tests {
+		if got := splitWords(tt.in); !reflect.DeepEqual(got, tt.words) {
+			t.Errorf("splitWords(%q) = %q, want %q", tt.in, got, tt.words)
+		}
+		if got := toSnakeCase(tt.in); got != tt.snake {
+			t.Errorf("toSnakeCase(%q) = %q, want %q", tt.in, got, tt.snake)
+		}
+		if got := toPascalCase(tt.in); got != tt.pascal {
+			t.Errorf("toPascalCase(%q) = %q, want %q", tt.in, got, tt.pascal)
+		}
+		if got := toLowerCamelCase(tt.in); got != tt.camel {
+			t.Errorf("toLowerCamelCase(%q) = %q, want %q", tt.in, got, tt.camel)
+		}
+	}
+}
+
+func TestNewResource(t *testing.T) {
+	tests := []struct {
+		in, table, pluralStruct, routeFile string
+	}{
+		{"UserID", "user_ids", "UserIDs", "app/routes/userIDs.go"},
+		{"url", "urls", "URLs", "app/routes/urls.go"},
+		{"URLs", "urls", "URLs", "app/routes/urls.go"},
+		{"IPv6Address", "ipv6_addresses", "IPv6Addresses", "app/routes/ipv6Addresses.go"},
+		{"KnowledgeBases", "knowledge_bases", "KnowledgeBases", "app/routes/knowledgeBases.go"},
+		{"user", "users", "Users", "app/routes/users.go"},
+	}
+
+	for _, tt := range tests {
+		res, err := newResource(tt.in, inflect.New())
+		if err != nil {
+			t.Errorf("newResource(%q): %v", tt.in, err)
+			continue
+		}
+		if res.pluralName != tt.table || res.pluralStruct != tt.pluralStruct || res.routeFile != tt.routeFile {
+			t.Errorf("newResource(%q) = %s, %s, %s, want %s, %s, %s", tt.in,
+				res.pluralName, res.pluralStruct, res.routeFile, tt.table, tt.pluralStruct, tt.routeFile)
+		}
+	}
+}
@@ -0,0 +1,296 @@
+package scaffold
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// route is a handler registration generated for a scaffolded resource.
+type route struct {
+	Method  string `json:"method"` // echo method name, e.g. GET
+	Path    string `json:"path"`
+	Handler string `json:"handler"` // function in app/routes
+}
+
+func (r route) key() string {
+	return r.Method + " " + r.Path
+}
+
+var httpMethods = map[string]bool{
+	"GET": true, "POST": true, "PUT": true, "PATCH": true, "DELETE": true,
+	"HEAD": true, "OPTIONS": true, "CONNECT": true, "TRACE": true, "Any": true,
+}
+
+// crudRoutes lists the routes of the handlers the route template generates.
+func crudRoutes(urlPath, structName, pluralFunc string) []route {
+	return []route{
+		{"GET", "/" + urlPath, "Get" + pluralFunc},
+		{"GET", "/" + urlPath + "/:id", "Get" + structName},
+		{"POST", "/" + urlPath, "Create" + structName},
+		{"PUT", "/" + urlPath + "/:id", "Update" + structName},
+		{"PATCH", "/" + urlPath + "/:id", "Patch" + structName},
+		{"DELETE", "/" + urlPath + "/:id", "Delete" + structName},
+	}
+}
+
+// nestedRoute lists the resources belonging to a referenced one, e.g.
+// GET /posts/:post_id/comments.
+func nestedRoute(refPath, refParam, urlPath, refStruct, pluralFunc string) route {
+	return route{"GET", "/" + refPath + "/:" + refParam + "/" + urlPath, "Get" + refStruct + pluralFunc}
+}
+
+// serverFile is a parsed main.go with the echo server set up in its main
+// func.
+type serverFile struct {
+	src       []byte
+	fset      *token.FileSet
+	body      *ast.BlockStmt
+	setup     ast.Stmt // the `e := echo.New()` statement
+	server    string   // name of the echo.New() variable
+	routesPkg string   // name app/routes is imported under
+}
+
+// parseServerFile parses file, failing if it no longer has the shape gecho
+// init generates: an echo.New() server in func main and an import of
+// app/routes.
+func parseServerFile(file, moduleName string) (*serverFile, error) {
+	src, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, file, src, parser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+
+	sf := &serverFile{src: src, fset: fset}
+	echoPkg := importName(f, "github.com/labstack/echo/v4")
+	sf.routesPkg = importName(f, moduleName+"/app/routes")
+	if echoPkg == "" || sf.routesPkg == "" {
+		return nil, fmt.Errorf("%s does not import echo and %s/app/routes", file, moduleName)
+	}
+	for _, decl := range f.Decls {
+		if fn, ok := decl.(*ast.FuncDecl); ok && fn.Name.Name == "main" && fn.Recv == nil {
+			sf.body = fn.Body
+		}
+	}
+	if sf.body == nil {
+		return nil, fmt.Errorf("%s has no func main", file)
+	}
+	for _, stmt := range sf.body.List {
+		if name, ok := echoServer(stmt, echoPkg); ok {
+			sf.setup, sf.server = stmt, name
+			break
+		}
+	}
+	if sf.server == "" {
+		return nil, errors.New("no " + echoPkg + ".New() server found in func main")
+	}
+	return sf, nil
+}
+
+func (sf *serverFile) offset(pos token.Pos) int {
+	return sf.fset.Position(pos).Offset
+}
+
+// writeFormatted formats src and writes it to file.
+func writeFormatted(file string, src []byte) error {
+	formatted, err := format.Source(src)
+	if err != nil {
+		return fmt.Errorf("failed to format %s: %w", file, err)
+	}
+	return os.WriteFile(file, formatted, 0644)
+}
+
+// registerRoutes adds the routes missing from the main func of file,
+// after its last registration of an app/routes handler. Routes already
+// registered for the same method and path are left alone, so running it
+// twice changes nothing. It returns the routes it added.
+func registerRoutes(file, moduleName string, routes []route) ([]route, error) {
+	src, added, err := withRoutes(file, moduleName, routes)
+	if err != nil || len(added) == 0 {
+		return nil, err
+	}
+	if err := os.WriteFile(file, src, 0644); err != nil {
+		return nil, err
+	}
+	return added, nil
+}
+
+// withRoutes returns the formatted source of file with the missing routes
+// added, and the routes it added.
+func withRoutes(file, moduleName string, routes []route) ([]byte, []route, error) {
+	sf, err := parseServerFile(file, moduleName)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var (
+		anchor   = sf.setup
+		existing = make(map[string]bool)
+	)
+	for _, stmt := range sf.body.List {
+		method, path, handler, ok := routeCall(stmt, sf.server)
+		if !ok {
+			continue
+		}
+		existing[method+" "+path] = true
+		if strings.HasPrefix(handler, sf.routesPkg+".") {
+			anchor = stmt
+		}
+	}
+
+	var added []route
+	var lines strings.Builder
+	for _, r := range routes {
+		if existing[r.key()] {
+			continue
+		}
+		added = append(added, r)
+		fmt.Fprintf(&lines, "\n\t%s.%s(%q, %s.%s)", sf.server, r.Method, r.Path, sf.routesPkg, r.Handler)
+	}
+	if len(added) == 0 {
+		return sf.src, nil, nil
+	}
+
+	at := sf.offset(anchor.End())
+	out := string(sf.src[:at]) + "\n" + lines.String() + string(sf.src[at:])
+	formatted, err := format.Source([]byte(out))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to format %s: %w", file, err)
+	}
+	return formatted, added, nil
+}
+
+// unregisterRoutes removes the registrations of routes from the main func
+// of file, matching method, path and handler. It returns the routes it
+// removed.
+func unregisterRoutes(file, moduleName string, routes []route) ([]route, error) {
+	sf, err := parseServerFile(file, moduleName)
+	if err != nil {
+		return nil, err
+	}
+
+	wanted := make(map[string]route, len(routes))
+	for _, r := range routes {
+		wanted[r.key()] = r
+	}
+
+	var (
+		removed []route
+		out     = sf.src
+	)
+	// Cut from the end so earlier offsets stay valid.
+	for i := len(sf.body.List) - 1; i >= 0; i-- {
+		stmt := sf.body.List[i]
+		method, path, handler, ok := routeCall(stmt, sf.server)
+		if !ok {
+			continue
+		}
+		r, ok := wanted[method+" "+path]
+		if !ok || handler != sf.routesPkg+"."+r.Handler {
+			continue
+		}
+		start, end := sf.offset(stmt.Pos()), sf.offset(stmt.End())
+		start = bytes.LastIndexByte(out[:start], '\n') + 1
+		if nl := bytes.IndexByte(out[end:], '\n'); nl >= 0 {
+			end += nl + 1
+		}
+		out = append(out[:start:start], out[end:]...)
+		removed = append(removed, r)
+	}
+	if len(removed) == 0 {
+		return nil, nil
+	}
+	if err := writeFormatted(file, out); err != nil {
+		return nil, err
+	}
+	return removed, nil
+}
+
+// importName returns the name path is imported under in f, or "" if f
+// does not import it.
+func importName(f *ast.File, path string) string {
+	for _, imp := range f.Imports {
+		p, err := strconv.Unquote(imp.Path.Value)
+		if err != nil || p != path {
+			continue
+		}
+		if imp.Name != nil {
+			return imp.Name.Name
+		}
+		name := path[strings.LastIndex(path, "/")+1:]
+		if name == "v4" {
+			name = "echo"
+		}
+		return name
+	}
+	return ""
+}
+
+// echoServer matches `e := echo.New()`, returning e.
+func echoServer(stmt ast.Stmt, echoPkg string) (string, bool) {
+	assign, ok := stmt.(*ast.AssignStmt)
+	if !ok || len(assign.Lhs) != 1 || len(assign.Rhs) != 1 {
+		return "", false
+	}
+	ident, ok := assign.Lhs[0].(*ast.Ident)
+	if !ok {
+		return "", false
+	}
+	call, ok := assign.Rhs[0].(*ast.CallExpr)
+	if !ok {
+		return "", false
+	}
+	sel, ok := call.Fun.(*ast.SelectorExpr)
+	if !ok || sel.Sel.Name != "New" {
+		return "", false
+	}
+	if pkg, ok := sel.X.(*ast.Ident); !ok || pkg.Name != echoPkg {
+		return "", false
+	}
+	return ident.Name, true
+}
+
+// routeCall matches `server.METHOD("/path", handler)`, returning the
+// method, the path and the handler if it is a pkg.Func selector.
+func routeCall(stmt ast.Stmt, server string) (method, path, handler string, ok bool) {
+	expr, isExpr := stmt.(*ast.ExprStmt)
+	if !isExpr {
+		return "", "", "", false
+	}
+	call, isCall := expr.X.(*ast.CallExpr)
+	if !isCall || len(call.Args) < 2 {
+		return "", "", "", false
+	}
+	sel, isSel := call.Fun.(*ast.SelectorExpr)
+	if !isSel || !httpMethods[sel.Sel.Name] {
+		return "", "", "", false
+	}
+	if recv, isIdent := sel.X.(*ast.Ident); !isIdent || recv.Name != server {
+		return "", "", "", false
+	}
+	lit, isLit := call.Args[0].(*ast.BasicLit)
+	if !isLit || lit.Kind != token.STRING {
+		return "", "", "", false
+	}
+	path, err := strconv.Unquote(lit.Value)
+	if err != nil {
+		return "", "", "", false
+	}
+	if fn, isSel := call.Args[1].(*ast.SelectorExpr); isSel {
+		if pkg, isIdent := fn.X.(*ast.Ident); isIdent {
+			handler = pkg.Name + "." + fn.Sel.Name
+		}
+	}
+	return sel.Sel.Name, path, handler, true
+}
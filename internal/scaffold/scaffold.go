@@ -1,218 +1,412 @@
 package scaffold
 
 import (
+	"errors"
 	"fmt"
+	"go/token"
+	"io/fs"
 	"log"
 	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
 	"strings"
-	"time"
-)
 
-func Run(rawName string) error {
-	moduleName := getModuleName("go.mod")
-	if rawName == "" {
-		return fmt.Errorf("missing model name")
-	}
-
-	timestamp := time.Now().Format("20060102150405")
-	migrationDir := "db/migrations"
-	modelsDir := "app/models"
-	routesDir := "app/routes"
-	queriesDir := "app/services/database"
+	"github.com/Juksefantomet/gecho/internal/inflect"
+	"github.com/Juksefantomet/gecho/internal/migrate"
+	"github.com/Juksefantomet/gecho/internal/templates"
+)
 
-	snakeName := strings.ToLower(strings.ReplaceAll(rawName, " ", "_"))
-	structName := toPascalCase(singular(snakeName))
-	pluralName := plural(snakeName)
-	lowerCamel := toLowerCamelCase(structName)
+// Where gecho projects keep each kind of generated file.
+const (
+	mainFile     = "main.go"
+	migrationDir = "db/migrations"
+	modelsDir    = "app/models"
+	routesDir    = "app/routes"
+	queriesDir   = "app/services/database"
 
-	upFile := fmt.Sprintf("%s/%s_%s.up.sql", migrationDir, timestamp, snakeName)
-	downFile := fmt.Sprintf("%s/%s_%s.down.sql", migrationDir, timestamp, snakeName)
+	// dateFile declares models.Date for the models with date fields.
+	dateFile = modelsDir + "/date.go"
+)
 
-	modelFile := fmt.Sprintf("%s/%s.go", modelsDir, lowerCamel)
-	routeFile := fmt.Sprintf("%s/%ss.go", routesDir, lowerCamel)
-	queriesFile := fmt.Sprintf("%s/%sQueries.go", queriesDir, lowerCamel)
+// resource holds the names and paths derived from the name given to
+// scaffold and destroy. Plurals all come from the same inflection, so the
+// table, URL path, handlers and files agree.
+type resource struct {
+	snakeName    string // singular, e.g. order_item
+	structName   string // OrderItem
+	pluralName   string // order_items, the table and URL path
+	pluralStruct string // OrderItems, as in GetOrderItems
+	lowerCamel   string // orderItem
+
+	modelFile   string
+	routeFile   string
+	queriesFile string
+}
 
-	for _, dir := range []string{migrationDir, modelsDir, routesDir, queriesDir} {
-		if err := os.MkdirAll(dir, os.ModePerm); err != nil {
-			log.Fatalf("Failed to create %s: %v", dir, err)
-		}
+func newResource(rawName string, inflections *inflect.Rules) (resource, error) {
+	words := splitWords(rawName)
+	if err := checkWords(rawName, words); err != nil {
+		return resource{}, err
+	}
+	r := resource{snakeName: inflections.Singularize(strings.Join(words, "_"))}
+	r.pluralName = inflections.Pluralize(r.snakeName)
+	if token.IsKeyword(toLowerCamelCase(r.snakeName)) {
+		return resource{}, fmt.Errorf("name %q is a Go keyword", r.snakeName)
+	}
+	if reservedSQL[r.pluralName] {
+		return resource{}, fmt.Errorf("table name %q is a reserved word in Postgres", r.pluralName)
 	}
 
-	writeFile(upFile, fmt.Sprintf(`-- SQL UP Migration
-
-CREATE TABLE %s
-(
-    id         SERIAL PRIMARY KEY,
-    name       TEXT NOT NULL UNIQUE,
-    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
-    updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
-);
-`, pluralName))
-	writeFile(downFile, fmt.Sprintf(`-- SQL DOWN Migration
+	r.structName = toPascalCase(r.snakeName)
+	r.pluralStruct = toPascalCase(r.pluralName)
+	r.lowerCamel = toLowerCamelCase(r.structName)
 
-DROP TABLE IF EXISTS %s;
-`, pluralName))
+	r.modelFile = fmt.Sprintf("%s/%s.go", modelsDir, r.lowerCamel)
+	r.routeFile = fmt.Sprintf("%s/%s.go", routesDir, toLowerCamelCase(r.pluralStruct))
+	r.queriesFile = fmt.Sprintf("%s/%sQueries.go", queriesDir, r.lowerCamel)
 
-	writeFileIfNotExists(modelFile, modelBoilerplate(structName))
-	writeFileIfNotExists(routeFile, routeBoilerplate(structName, moduleName))
-	writeFileIfNotExists(queriesFile, queriesBoilerplate(structName, moduleName))
+	// Uncountable names (equipment) would give the list and the single
+	// item handlers the same name.
+	if r.pluralStruct == r.structName {
+		r.pluralStruct += "List"
+	}
+	return r, nil
+}
 
-	fmt.Printf("Scaffold created:\n  %s\n  %s\n  %s\n  %s\n, %s\n",
-		upFile, downFile, modelFile, routeFile, queriesFile)
-return nil
+// routes lists the routes registered for the resource's handlers: the
+// CRUD routes and one nested list route per references field.
+func (r resource) routes(fields []Field) []route {
+	routes := crudRoutes(r.pluralName, r.structName, r.pluralStruct)
+	for _, f := range fields {
+		if f.References != "" {
+			routes = append(routes, nestedRoute(f.RefTable, f.Name, r.pluralName, f.RefStruct(), r.pluralStruct))
+		}
+	}
+	return routes
+}
 
+// Data is what the scaffold templates are executed with. See package
+// templates for how each field is used.
+type Data struct {
+	Module     string
+	StructName string
+	PluralName string
+	TableName  string
+	URLPath    string
+	HumanName  string
+	IDFunc     string
+	Width      int
+	Fields     []Field
 }
 
-func getModuleName(path string) string {
-	data, err := os.ReadFile(path)
-	if err != nil {
-		log.Fatalf("Failed to read %s: %v", path, err)
+func newData(res resource, moduleName string, fields []Field) Data {
+	d := Data{
+		Module:     moduleName,
+		StructName: res.structName,
+		PluralName: res.pluralStruct,
+		TableName:  res.pluralName,
+		URLPath:    res.pluralName,
+		HumanName:  strings.ReplaceAll(res.snakeName, "_", " "),
+		IDFunc:     res.lowerCamel + "ID",
+		Width:      len("created_at"),
+		Fields:     fields,
 	}
-
-	lines := strings.Split(string(data), "\n")
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
-		if strings.HasPrefix(line, "module ") {
-			return strings.TrimSpace(strings.TrimPrefix(line, "module"))
-		}
+	for _, f := range fields {
+		d.Width = max(d.Width, len(f.Name))
 	}
-	log.Fatal("No module declaration found in go.mod")
-	return ""
+	return d
 }
 
-func toLowerCamelCase(s string) string {
-	if len(s) == 0 {
-		return s
+// References returns the references fields.
+func (d Data) References() []Field {
+	var refs []Field
+	for _, f := range d.Fields {
+		if f.References != "" {
+			refs = append(refs, f)
+		}
 	}
-	return strings.ToLower(s[:1]) + s[1:]
+	return refs
 }
 
-func toPascalCase(s string) string {
-	parts := strings.Split(s, "_")
-	for i := range parts {
-		if len(parts[i]) > 0 {
-			parts[i] = strings.ToUpper(parts[i][:1]) + parts[i][1:]
+// HasType reports whether any field has one of types.
+func (d Data) HasType(types ...string) bool {
+	for _, f := range d.Fields {
+		for _, t := range types {
+			if f.Type == t {
+				return true
+			}
 		}
 	}
-	return strings.Join(parts, "")
+	return false
 }
 
-func toSnakeCase(s string) string {
-	var result []rune
-	for i, r := range s {
-		if i > 0 && r >= 'A' && r <= 'Z' {
-			result = append(result, '_')
+// HasDefault reports whether any field has a default.
+func (d Data) HasDefault() bool {
+	for _, f := range d.Fields {
+		if f.Default != "" {
+			return true
 		}
-		result = append(result, rune(strings.ToLower(string(r))[0]))
 	}
-	return string(result)
+	return false
 }
 
-func writeFile(path string, content string) {
-	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
-		log.Fatalf("Failed to write %s: %v", path, err)
-	}
+// Options configures Run.
+type Options struct {
+	// DryRun prints every file with its content instead of writing it.
+	DryRun bool
+	// Diff prints a unified diff of every file against the one on disk
+	// instead of writing it.
+	Diff bool
+	// Force overwrites files that already exist, the resource's migration
+	// included.
+	Force bool
 }
 
-func writeFileIfNotExists(path string, content string) {
-	if _, err := os.Stat(path); os.IsNotExist(err) {
-		writeFile(path, content)
-	}
+// What Run does with a generated file.
+const (
+	actionCreate    = "create"
+	actionOverwrite = "overwrite"
+	actionSkip      = "skip"
+	actionIdentical = "identical"
+)
+
+// generatedFile is a file Run renders, with what it does with it.
+type generatedFile struct {
+	path     string
+	template string
+	content  string
+	existing string // content on disk, if any
+	action   string
+	shared   bool // used by other resources too, so not in the manifest
 }
 
-func plural(name string) string {
-	if strings.HasSuffix(name, "y") && !strings.HasSuffix(name, "ay") && !strings.HasSuffix(name, "ey") {
-		return strings.TrimSuffix(name, "y") + "ies"
-	}
-	if strings.HasSuffix(name, "s") {
-		return name + "es"
+// plan decides the action for f from what is on disk.
+func (f *generatedFile) plan(force bool) error {
+	data, err := os.ReadFile(f.path)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		f.action = actionCreate
+	case err != nil:
+		return fmt.Errorf("failed to read %s: %w", f.path, err)
+	case string(data) == f.content:
+		f.existing, f.action = string(data), actionIdentical
+	case force:
+		f.existing, f.action = string(data), actionOverwrite
+	default:
+		f.existing, f.action = string(data), actionSkip
 	}
-	return name + "s"
+	return nil
 }
 
-func singular(name string) string {
-	if strings.HasSuffix(name, "ies") {
-		return strings.TrimSuffix(name, "ies") + "y"
+// existingMigration returns the path, without .up.sql, of the migration an
+// earlier scaffold of the resource created, or "" if there is none: the one
+// its manifest records, else the oldest <timestamp>_<name>.up.sql. Rerunning
+// scaffold compares against that migration instead of adding another
+// CREATE TABLE.
+func existingMigration(snakeName string) (string, error) {
+	matches, err := filepath.Glob(filepath.Join(migrationDir, "*_"+snakeName+".up.sql"))
+	if err != nil {
+		return "", err
 	}
-	if strings.HasSuffix(name, "es") {
-		return strings.TrimSuffix(name, "es")
+	pattern := regexp.MustCompile(`^\d{14}_` + regexp.QuoteMeta(snakeName) + `\.up\.sql$`)
+	var found []string
+	for _, path := range matches {
+		if pattern.MatchString(filepath.Base(path)) {
+			found = append(found, strings.TrimSuffix(filepath.ToSlash(path), ".up.sql"))
+		}
 	}
-	if strings.HasSuffix(name, "s") {
-		return strings.TrimSuffix(name, "s")
+	if len(found) == 0 {
+		return "", nil
 	}
-	return name
+	sort.Strings(found)
+
+	m, err := loadManifest(snakeName)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return "", err
+	}
+	if m != nil {
+		for _, path := range found {
+			if _, ok := m.Files[path+".up.sql"]; ok {
+				return path, nil
+			}
+		}
+	}
+	return found[0], nil
 }
 
-func modelBoilerplate(structName string) string {
-	return fmt.Sprintf(`package models
+// Run generates the migration, model, route and queries of a resource.
+// fieldArgs are name:type[:modifier] definitions, see ParseFields.
+func Run(rawName string, fieldArgs []string, opts Options) error {
+	moduleName := getModuleName("go.mod")
+	if rawName == "" {
+		return fmt.Errorf("missing model name")
+	}
+	inflections, err := inflect.Load()
+	if err != nil {
+		return err
+	}
+	fields, err := ParseFields(fieldArgs, inflections)
+	if err != nil {
+		return err
+	}
 
-import "time"
+	res, err := newResource(rawName, inflections)
+	if err != nil {
+		return err
+	}
+	migration, err := existingMigration(res.snakeName)
+	if err != nil {
+		return err
+	}
+	if migration == "" {
+		timestamp, err := migrate.Timestamp(migrationDir)
+		if err != nil {
+			return err
+		}
+		migration = fmt.Sprintf("%s/%s_%s", migrationDir, timestamp, res.snakeName)
+	}
+	routes := res.routes(fields)
+	data := newData(res, moduleName, fields)
+	if data.HasType("date") && res.modelFile == dateFile {
+		return fmt.Errorf("%s would replace the models.Date type of its date fields; pick another name", res.snakeName)
+	}
 
-type %s struct {
-	ID        int       `+"`json:\"id\"`"+`
-	// populate the rest of the model here
-	// Example  string    `+"`json:\"example\"`"+`
+	files := []*generatedFile{
+		{path: migration + ".up.sql", template: "scaffold/up.sql.tmpl"},
+		{path: migration + ".down.sql", template: "scaffold/down.sql.tmpl"},
+		{path: res.modelFile, template: "scaffold/model.go.tmpl"},
+		{path: res.routeFile, template: "scaffold/route.go.tmpl"},
+		{path: res.queriesFile, template: "scaffold/queries.go.tmpl"},
+	}
+	if data.HasType("date") {
+		files = append(files, &generatedFile{path: dateFile, template: "scaffold/date.go.tmpl", shared: true})
+	}
+	// Render everything first so a broken template writes nothing.
+	for _, f := range files {
+		if f.content, err = templates.Render(f.template, data); err != nil {
+			return err
+		}
+		if err := f.plan(opts.Force); err != nil {
+			return err
+		}
+	}
 
-	CreatedAt time.Time `+"`gorm:\"autoCreateTime\"`"+`
-	UpdatedAt time.Time `+"`gorm:\"autoUpdateTime\"`"+`
-}
-`, structName)
-}
+	if opts.DryRun || opts.Diff {
+		return preview(files, moduleName, routes, opts)
+	}
 
-func queriesBoilerplate(structName, moduleName string) string {
-	funcName := "Get" + structName + "s"
-	tableName := toSnakeCase(structName) + "s"
+	for _, dir := range []string{migrationDir, modelsDir, routesDir, queriesDir} {
+		if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+			return fmt.Errorf("failed to create %s: %w", dir, err)
+		}
+	}
+	m := newManifest(res.snakeName)
+	for _, f := range files {
+		if f.action == actionCreate || f.action == actionOverwrite {
+			if err := os.WriteFile(f.path, []byte(f.content), 0644); err != nil {
+				return fmt.Errorf("failed to write %s: %w", f.path, err)
+			}
+		}
+		if f.action != actionSkip && !f.shared {
+			m.add(f.path, f.content)
+		}
+	}
+	m.Routes = routes
+	if err := m.save(); err != nil {
+		return err
+	}
 
-	return fmt.Sprintf(`package database
+	fmt.Printf("Scaffold %s:\n", res.snakeName)
+	printActions(files)
 
-import (
-	"%[3]s/app/models"
-)
+	for _, f := range fields {
+		if f.References == "" {
+			continue
+		}
+		refModel := fmt.Sprintf("%s/%s.go", modelsDir, toLowerCamelCase(f.References))
+		if _, err := os.Stat(refModel); err != nil {
+			fmt.Printf("⚠ %s not found: scaffold %s first, as the model and migration of %s refer to it.\n",
+				refModel, f.References, res.snakeName)
+		}
+	}
 
-// %[1]s returns all %[2]s
-func %[1]s() ([]models.%[4]s, error) {
-	var items []models.%[4]s
-	err := GetDB().Raw("SELECT * FROM %[2]s").Scan(&items).Error
-	return items, err
-}
-`, funcName, tableName, moduleName, structName)
+	added, err := registerRoutes(mainFile, moduleName, routes)
+	if err != nil {
+		fmt.Printf("⚠ Could not register routes in %s (%v). Add them by hand:\n", mainFile, err)
+		for _, r := range routes {
+			fmt.Printf("  e.%s(%q, routes.%s)\n", r.Method, r.Path, r.Handler)
+		}
+	} else if len(added) > 0 {
+		fmt.Printf("Registered %d route(s) in %s.\n", len(added), mainFile)
+	} else {
+		fmt.Printf("Routes already registered in %s.\n", mainFile)
+	}
+	return nil
 }
 
-func routeBoilerplate(structName, moduleName string) string {
-	jsonTag := toSnakeCase(structName) + "s"
-	funcName := "Get" + structName + "s"
-	responseStruct := structName + "Response"
-	responseField := structName + "s"
-
-	return fmt.Sprintf(`package routes
+// preview prints what Run would do, as file contents or as diffs, without
+// writing anything.
+func preview(files []*generatedFile, moduleName string, routes []route, opts Options) error {
+	for _, f := range files {
+		switch {
+		case opts.Diff:
+			from := f.path
+			if f.action == actionCreate {
+				from = ""
+			}
+			fmt.Print(unifiedDiff(from, f.path, f.existing, f.content))
+		default:
+			fmt.Printf("==> %s %s\n%s\n", f.action, f.path, f.content)
+		}
+	}
 
-import (
-	"net/http"
-	"%[4]s/app/models"
-	"%[4]s/app/services/database"
+	src, added, err := withRoutes(mainFile, moduleName, routes)
+	switch {
+	case err != nil:
+		fmt.Printf("⚠ Could not register routes in %s (%v).\n", mainFile, err)
+	case opts.Diff:
+		current, err := os.ReadFile(mainFile)
+		if err != nil {
+			return err
+		}
+		fmt.Print(unifiedDiff(mainFile, mainFile, string(current), string(src)))
+	default:
+		fmt.Printf("==> register %d route(s) in %s\n", len(added), mainFile)
+		for _, r := range added {
+			fmt.Printf("  %-6s %s → routes.%s\n", r.Method, r.Path, r.Handler)
+		}
+		fmt.Println()
+	}
 
-	"github.com/labstack/echo/v4"
-)
+	fmt.Println("Dry run, nothing was written. Would:")
+	printActions(files)
+	return nil
+}
 
-// %[2]s defines the response structure
-type %[2]s struct {
-	%[5]s []models.%[1]s `+"`json:\"%[6]s\"`"+`
+func printActions(files []*generatedFile) {
+	for _, f := range files {
+		note := ""
+		if f.action == actionSkip {
+			note = " (exists and differs; --force overwrites it, --diff shows how)"
+		}
+		fmt.Printf("  %-10s %s%s\n", f.action, f.path, note)
+	}
 }
 
-// @Summary %[6]s
-// @Description Returns all %[6]s
-// @Tags %[6]s
-// @Accept json
-// @Produce json
-// @Success 200 {object} %[2]s
-// @Router /%[6]s [get]
-func %[3]s(c echo.Context) error {
-	items, err := database.%[3]s()
+func getModuleName(path string) string {
+	data, err := os.ReadFile(path)
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to fetch %[6]s"})
+		log.Fatalf("Failed to read %s: %v", path, err)
 	}
 
-	return c.JSON(http.StatusOK, %[2]s{%[5]s: items})
-}
-`, structName, responseStruct, funcName, moduleName, responseField, jsonTag)
+	lines := strings.Split(string(data), "\n")
+	for _, line := range lines {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "module ") {
+			return strings.TrimSpace(strings.TrimPrefix(line, "module"))
+		}
+	}
+	log.Fatal("No module declaration found in go.mod")
+	return ""
 }
@@ -0,0 +1,531 @@
+// Package schema keeps db/schema.sql in sync with the database by
+// introspecting pg_catalog, and loads that file into a fresh database.
+package schema
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gorm.io/gorm"
+
+	"github.com/Juksefantomet/gecho/migrate"
+	"github.com/Juksefantomet/gecho/seed"
+)
+
+// DefaultPath is where gecho projects keep their schema dump.
+const DefaultPath = "db/schema.sql"
+
+const header = `-- Generated by gecho from the database schema. Do not edit by hand:
+-- it is rewritten after every gecho migrate and loaded by gecho db schema:load.
+
+SET check_function_bodies = false;
+`
+
+// WriteFile dumps the current schema of db to path. The file is only
+// replaced once the whole dump succeeded.
+func WriteFile(db *gorm.DB, path string) error {
+	var b bytes.Buffer
+	if err := Dump(db, &b); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		return fmt.Errorf("failed to create %s: %w", filepath.Dir(path), err)
+	}
+	if err := os.WriteFile(path, b.Bytes(), 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return nil
+}
+
+// Dump writes the schema db currently uses as SQL that recreates it. Objects
+// are emitted in dependency order and sorted by name within each section,
+// so the output only changes when the schema does. Rows of the migrations
+// table are included so a loaded database knows which migrations it has.
+func Dump(db *gorm.DB, w io.Writer) error {
+	var schema string
+	if err := db.Raw("SELECT current_schema()").Scan(&schema).Error; err != nil {
+		return fmt.Errorf("failed to read current schema: %w", err)
+	}
+
+	d := dumper{db: db, schema: schema, b: new(bytes.Buffer)}
+	d.b.WriteString(header)
+	for _, section := range []func() error{
+		d.extensions,
+		d.enums,
+		d.functions,
+		d.sequences,
+		d.tables,
+		d.ownedSequences,
+		d.constraints,
+		d.views,
+		d.indexes,
+		d.triggers,
+		d.migrations,
+	} {
+		if err := section(); err != nil {
+			return err
+		}
+	}
+	_, err := d.b.WriteTo(w)
+	return err
+}
+
+type dumper struct {
+	db     *gorm.DB
+	schema string
+	b      *bytes.Buffer
+}
+
+// query scans the rows of sql into dest. The dumped schema is passed as $1
+// and the seeds table, which only exists once seeds ran and so is not part
+// of the schema migrations produce, as $2.
+func (d *dumper) query(dest any, what, sql string) error {
+	if !strings.Contains(sql, "$2") {
+		return d.scan(dest, what, sql, d.schema)
+	}
+	return d.scan(dest, what, sql, d.schema, seed.DefaultTableName)
+}
+
+func (d *dumper) scan(dest any, what, sql string, args ...any) error {
+	if err := d.db.Raw(sql, args...).Scan(dest).Error; err != nil {
+		return fmt.Errorf("failed to read %s: %w", what, err)
+	}
+	return nil
+}
+
+// section starts a new block of statements under a comment.
+func (d *dumper) section(title string) {
+	fmt.Fprintf(d.b, "\n-- %s\n\n", title)
+}
+
+func (d *dumper) extensions() error {
+	var names []string
+	err := d.query(&names, "extensions", `
+		SELECT quote_ident(e.extname)
+		FROM pg_extension e
+		JOIN pg_namespace n ON n.oid = e.extnamespace
+		WHERE n.nspname = $1
+		ORDER BY e.extname COLLATE "C"`)
+	if err != nil || len(names) == 0 {
+		return err
+	}
+	d.section("Extensions")
+	for _, name := range names {
+		fmt.Fprintf(d.b, "CREATE EXTENSION IF NOT EXISTS %s;\n", name)
+	}
+	return nil
+}
+
+func (d *dumper) enums() error {
+	var enums []struct {
+		Name   string
+		Labels string
+	}
+	err := d.query(&enums, "enum types", `
+		SELECT quote_ident(t.typname) AS name,
+		       string_agg(quote_literal(e.enumlabel), ', ' ORDER BY e.enumsortorder) AS labels
+		FROM pg_type t
+		JOIN pg_namespace n ON n.oid = t.typnamespace
+		JOIN pg_enum e ON e.enumtypid = t.oid
+		WHERE n.nspname = $1
+		  AND NOT EXISTS (SELECT 1 FROM pg_depend d WHERE d.objid = t.oid AND d.deptype = 'e')
+		GROUP BY t.typname
+		ORDER BY t.typname COLLATE "C"`)
+	if err != nil || len(enums) == 0 {
+		return err
+	}
+	d.section("Types")
+	for _, e := range enums {
+		fmt.Fprintf(d.b, "CREATE TYPE %s AS ENUM (%s);\n", e.Name, e.Labels)
+	}
+	return nil
+}
+
+func (d *dumper) functions() error {
+	var defs []string
+	err := d.query(&defs, "functions", `
+		SELECT pg_get_functiondef(p.oid)
+		FROM pg_proc p
+		JOIN pg_namespace n ON n.oid = p.pronamespace
+		WHERE n.nspname = $1
+		  AND p.prokind IN ('f', 'p')
+		  AND NOT EXISTS (SELECT 1 FROM pg_depend d WHERE d.objid = p.oid AND d.deptype = 'e')
+		ORDER BY p.proname COLLATE "C", pg_get_function_identity_arguments(p.oid) COLLATE "C"`)
+	if err != nil || len(defs) == 0 {
+		return err
+	}
+	d.section("Functions")
+	for i, def := range defs {
+		if i > 0 {
+			d.b.WriteString("\n")
+		}
+		fmt.Fprintf(d.b, "%s;\n", strings.TrimRight(def, " \t\r\n"))
+	}
+	return nil
+}
+
+// sequences dumps standalone and serial sequences. Identity sequences are
+// recreated by their column's GENERATED ... AS IDENTITY clause.
+func (d *dumper) sequences() error {
+	var seqs []struct {
+		Name      string
+		DataType  string
+		Start     int64
+		Increment int64
+		Min       int64
+		Max       int64
+		Cache     int64
+		Cycle     bool
+	}
+	err := d.query(&seqs, "sequences", `
+		SELECT quote_ident(c.relname) AS name,
+		       format_type(s.seqtypid, NULL) AS data_type,
+		       s.seqstart AS start, s.seqincrement AS increment,
+		       s.seqmin AS min, s.seqmax AS max,
+		       s.seqcache AS cache, s.seqcycle AS cycle
+		FROM pg_sequence s
+		JOIN pg_class c ON c.oid = s.seqrelid
+		JOIN pg_namespace n ON n.oid = c.relnamespace
+		WHERE n.nspname = $1
+		  AND NOT EXISTS (SELECT 1 FROM pg_depend d WHERE d.objid = c.oid AND d.deptype IN ('i', 'e'))
+		  AND NOT EXISTS (SELECT 1 FROM pg_depend d JOIN pg_class t ON t.oid = d.refobjid
+		                  WHERE d.objid = c.oid AND d.deptype = 'a' AND t.relname = $2)
+		ORDER BY c.relname COLLATE "C"`)
+	if err != nil || len(seqs) == 0 {
+		return err
+	}
+	d.section("Sequences")
+	for i, s := range seqs {
+		if i > 0 {
+			d.b.WriteString("\n")
+		}
+		fmt.Fprintf(d.b, "CREATE SEQUENCE %s\n    AS %s\n    START WITH %d\n    INCREMENT BY %d\n    MINVALUE %d\n    MAXVALUE %d\n    CACHE %d",
+			s.Name, s.DataType, s.Start, s.Increment, s.Min, s.Max, s.Cache)
+		if s.Cycle {
+			d.b.WriteString("\n    CYCLE")
+		}
+		d.b.WriteString(";\n")
+	}
+	return nil
+}
+
+type column struct {
+	Name      string
+	Type      string
+	NotNull   bool
+	Default   string
+	Identity  string
+	Generated string
+}
+
+func (c column) definition() string {
+	def := c.Name + " " + c.Type
+	switch {
+	case c.Generated == "s":
+		def += " GENERATED ALWAYS AS (" + c.Default + ") STORED"
+	case c.Default != "":
+		def += " DEFAULT " + c.Default
+	}
+	switch c.Identity {
+	case "a":
+		def += " GENERATED ALWAYS AS IDENTITY"
+	case "d":
+		def += " GENERATED BY DEFAULT AS IDENTITY"
+	}
+	if c.NotNull {
+		def += " NOT NULL"
+	}
+	return def
+}
+
+func (d *dumper) tables() error {
+	var tables []struct {
+		OID       uint32 `gorm:"column:oid"`
+		Name      string
+		PartKey   string
+		Parent    string
+		Partition string
+	}
+	err := d.query(&tables, "tables", `
+		SELECT c.oid,
+		       quote_ident(c.relname) AS name,
+		       CASE WHEN c.relkind = 'p' THEN pg_get_partkeydef(c.oid) ELSE '' END AS part_key,
+		       COALESCE((SELECT quote_ident(p.relname) FROM pg_inherits i JOIN pg_class p ON p.oid = i.inhparent
+		                 WHERE i.inhrelid = c.oid AND c.relispartition), '') AS parent,
+		       CASE WHEN c.relispartition THEN pg_get_expr(c.relpartbound, c.oid) ELSE '' END AS partition
+		FROM pg_class c
+		JOIN pg_namespace n ON n.oid = c.relnamespace
+		WHERE n.nspname = $1
+		  AND c.relkind IN ('r', 'p') AND c.relname <> $2
+		  AND NOT EXISTS (SELECT 1 FROM pg_depend d WHERE d.objid = c.oid AND d.deptype = 'e')
+		ORDER BY c.relispartition, c.relname COLLATE "C"`)
+	if err != nil || len(tables) == 0 {
+		return err
+	}
+
+	d.section("Tables")
+	for i, t := range tables {
+		if i > 0 {
+			d.b.WriteString("\n")
+		}
+		if t.Parent != "" {
+			fmt.Fprintf(d.b, "CREATE TABLE %s PARTITION OF %s %s;\n", t.Name, t.Parent, t.Partition)
+			continue
+		}
+
+		var columns []column
+		err := d.scan(&columns, "columns of "+t.Name, `
+			SELECT quote_ident(a.attname) AS name,
+			       format_type(a.atttypid, a.atttypmod) AS type,
+			       a.attnotnull AS not_null,
+			       COALESCE(pg_get_expr(ad.adbin, ad.adrelid), '') AS "default",
+			       a.attidentity::text AS identity,
+			       a.attgenerated::text AS generated
+			FROM pg_attribute a
+			LEFT JOIN pg_attrdef ad ON ad.adrelid = a.attrelid AND ad.adnum = a.attnum
+			WHERE a.attrelid = $1 AND a.attnum > 0 AND NOT a.attisdropped
+			ORDER BY a.attnum`, t.OID)
+		if err != nil {
+			return err
+		}
+
+		defs := make([]string, len(columns))
+		for j, c := range columns {
+			defs[j] = "    " + c.definition()
+		}
+		fmt.Fprintf(d.b, "CREATE TABLE %s (\n%s\n)", t.Name, strings.Join(defs, ",\n"))
+		if t.PartKey != "" {
+			fmt.Fprintf(d.b, " PARTITION BY %s", t.PartKey)
+		}
+		d.b.WriteString(";\n")
+	}
+	return nil
+}
+
+// ownedSequences ties serial sequences to their columns so dropping the
+// column drops the sequence, as it does in the migrated database.
+func (d *dumper) ownedSequences() error {
+	var owned []string
+	err := d.query(&owned, "sequence ownership", `
+		SELECT format('ALTER SEQUENCE %I OWNED BY %I.%I;', s.relname, t.relname, a.attname)
+		FROM pg_depend d
+		JOIN pg_class s ON s.oid = d.objid AND s.relkind = 'S'
+		JOIN pg_class t ON t.oid = d.refobjid
+		JOIN pg_attribute a ON a.attrelid = t.oid AND a.attnum = d.refobjsubid
+		JOIN pg_namespace n ON n.oid = s.relnamespace
+		WHERE d.classid = 'pg_class'::regclass AND d.deptype = 'a' AND n.nspname = $1 AND t.relname <> $2
+		ORDER BY s.relname COLLATE "C"`)
+	if err != nil || len(owned) == 0 {
+		return err
+	}
+	d.b.WriteString("\n")
+	for _, stmt := range owned {
+		d.b.WriteString(stmt + "\n")
+	}
+	return nil
+}
+
+// constraints dumps primary keys, unique, check and exclusion constraints
+// before foreign keys, which need the referenced keys to exist. Constraints
+// partitions inherit from their parent are recreated with it.
+func (d *dumper) constraints() error {
+	var cons []struct {
+		Table   string
+		Name    string
+		Def     string
+		Foreign bool
+	}
+	err := d.query(&cons, "constraints", `
+		SELECT quote_ident(t.relname) AS "table",
+		       quote_ident(c.conname) AS name,
+		       pg_get_constraintdef(c.oid) AS def,
+		       c.contype = 'f' AS "foreign"
+		FROM pg_constraint c
+		JOIN pg_class t ON t.oid = c.conrelid
+		JOIN pg_namespace n ON n.oid = t.relnamespace
+		WHERE n.nspname = $1 AND t.relname <> $2
+		  AND c.contype IN ('p', 'u', 'c', 'x', 'f')
+		  AND c.conislocal AND c.conparentid = 0
+		ORDER BY c.contype = 'f', t.relname COLLATE "C", c.conname COLLATE "C"`)
+	if err != nil || len(cons) == 0 {
+		return err
+	}
+
+	d.section("Constraints")
+	foreign := false
+	for _, c := range cons {
+		if c.Foreign && !foreign {
+			d.section("Foreign keys")
+			foreign = true
+		}
+		fmt.Fprintf(d.b, "ALTER TABLE %s ADD CONSTRAINT %s %s;\n", c.Table, c.Name, c.Def)
+	}
+	return nil
+}
+
+// views dumps views and materialized views, ordering them so every view
+// comes after the views it selects from.
+func (d *dumper) views() error {
+	var views []struct {
+		Name         string
+		Materialized bool
+		Def          string
+	}
+	err := d.query(&views, "views", `
+		SELECT quote_ident(c.relname) AS name,
+		       c.relkind = 'm' AS materialized,
+		       pg_get_viewdef(c.oid, true) AS def
+		FROM pg_class c
+		JOIN pg_namespace n ON n.oid = c.relnamespace
+		WHERE n.nspname = $1 AND c.relkind IN ('v', 'm')
+		  AND NOT EXISTS (SELECT 1 FROM pg_depend d WHERE d.objid = c.oid AND d.deptype = 'e')
+		ORDER BY c.relname COLLATE "C"`)
+	if err != nil || len(views) == 0 {
+		return err
+	}
+
+	var deps []struct {
+		View      string
+		DependsOn string
+	}
+	err = d.query(&deps, "view dependencies", `
+		SELECT DISTINCT quote_ident(v.relname) AS view, quote_ident(src.relname) AS depends_on
+		FROM pg_depend d
+		JOIN pg_rewrite r ON r.oid = d.objid
+		JOIN pg_class v ON v.oid = r.ev_class
+		JOIN pg_class src ON src.oid = d.refobjid
+		JOIN pg_namespace n ON n.oid = v.relnamespace
+		WHERE d.classid = 'pg_rewrite'::regclass AND d.refclassid = 'pg_class'::regclass
+		  AND n.nspname = $1 AND src.relnamespace = n.oid
+		  AND src.oid <> v.oid AND src.relkind IN ('v', 'm')`)
+	if err != nil {
+		return err
+	}
+	dependsOn := make(map[string][]string)
+	for _, dep := range deps {
+		dependsOn[dep.View] = append(dependsOn[dep.View], dep.DependsOn)
+	}
+	for _, names := range dependsOn {
+		sort.Strings(names)
+	}
+
+	byName := make(map[string]int, len(views))
+	for i, v := range views {
+		byName[v.Name] = i
+	}
+	written := make(map[string]bool, len(views))
+	count := 0
+	var write func(name string)
+	write = func(name string) {
+		if written[name] {
+			return
+		}
+		written[name] = true
+		for _, dep := range dependsOn[name] {
+			write(dep)
+		}
+		v := views[byName[name]]
+		def := strings.TrimSuffix(strings.TrimSpace(v.Def), ";")
+		if count > 0 {
+			d.b.WriteString("\n")
+		}
+		count++
+		if v.Materialized {
+			fmt.Fprintf(d.b, "CREATE MATERIALIZED VIEW %s AS\n%s\nWITH NO DATA;\n", v.Name, def)
+		} else {
+			fmt.Fprintf(d.b, "CREATE VIEW %s AS\n%s;\n", v.Name, def)
+		}
+	}
+
+	d.section("Views")
+	for _, v := range views {
+		write(v.Name)
+	}
+	return nil
+}
+
+// indexes dumps indexes not created by a constraint, including those on
+// materialized views. Indexes partitions inherit are recreated with their
+// parent's.
+func (d *dumper) indexes() error {
+	var defs []string
+	err := d.query(&defs, "indexes", `
+		SELECT pg_get_indexdef(ix.indexrelid)
+		FROM pg_index ix
+		JOIN pg_class i ON i.oid = ix.indexrelid
+		JOIN pg_class t ON t.oid = ix.indrelid
+		JOIN pg_namespace n ON n.oid = t.relnamespace
+		WHERE n.nspname = $1 AND t.relname <> $2
+		  AND NOT EXISTS (SELECT 1 FROM pg_constraint c WHERE c.conindid = ix.indexrelid AND c.contype IN ('p', 'u', 'x'))
+		  AND NOT EXISTS (SELECT 1 FROM pg_inherits inh WHERE inh.inhrelid = ix.indexrelid)
+		ORDER BY t.relname COLLATE "C", i.relname COLLATE "C"`)
+	if err != nil || len(defs) == 0 {
+		return err
+	}
+	d.section("Indexes")
+	for _, def := range defs {
+		d.b.WriteString(def + ";\n")
+	}
+	return nil
+}
+
+func (d *dumper) triggers() error {
+	var defs []string
+	err := d.query(&defs, "triggers", `
+		SELECT pg_get_triggerdef(tg.oid)
+		FROM pg_trigger tg
+		JOIN pg_class t ON t.oid = tg.tgrelid
+		JOIN pg_namespace n ON n.oid = t.relnamespace
+		WHERE n.nspname = $1 AND t.relname <> $2 AND NOT tg.tgisinternal AND tg.tgparentid = 0
+		ORDER BY t.relname COLLATE "C", tg.tgname COLLATE "C"`)
+	if err != nil || len(defs) == 0 {
+		return err
+	}
+	d.section("Triggers")
+	for _, def := range defs {
+		d.b.WriteString(def + ";\n")
+	}
+	return nil
+}
+
+// migrations records every applied migration as part of a single batch.
+// Batch numbers and timestamps differ between environments, so keeping
+// them would make the dump change without the schema changing.
+func (d *dumper) migrations() error {
+	table := migrate.DefaultTableName
+	if !d.db.Migrator().HasTable(table) {
+		return nil
+	}
+	var rows []struct {
+		Name         string
+		UpChecksum   string
+		DownChecksum string
+	}
+	err := d.db.Table(table).
+		Select("name, up_checksum, down_checksum").
+		Order(`name COLLATE "C"`).
+		Find(&rows).Error
+	if err != nil {
+		return fmt.Errorf("failed to read %s table: %w", table, err)
+	}
+	if len(rows) == 0 {
+		return nil
+	}
+
+	d.section("Applied migrations")
+	fmt.Fprintf(d.b, "INSERT INTO %s (name, up_checksum, down_checksum, batch, applied_at) VALUES\n", table)
+	for i, r := range rows {
+		sep := ","
+		if i == len(rows)-1 {
+			sep = ";"
+		}
+		fmt.Fprintf(d.b, "    (%s, %s, %s, 1, now())%s\n",
+			migrate.QuoteLiteral(r.Name), migrate.QuoteLiteral(r.UpChecksum), migrate.QuoteLiteral(r.DownChecksum), sep)
+	}
+	return nil
+}
@@ -0,0 +1,46 @@
+package schema
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"gorm.io/gorm"
+
+	"github.com/Juksefantomet/gecho/migrate"
+)
+
+// ErrNotEmpty is returned by Load when the target schema already has
+// tables or views, which the loaded file would collide with.
+var ErrNotEmpty = errors.New("database schema is not empty, load db/schema.sql into a fresh database")
+
+// Load executes the schema dump at path against db in a single transaction,
+// one statement at a time so a failure names the line it happened on.
+func Load(db *gorm.DB, path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	var objects int64
+	err = db.Raw(`
+		SELECT count(*)
+		FROM pg_class c
+		JOIN pg_namespace n ON n.oid = c.relnamespace
+		WHERE n.nspname = current_schema() AND c.relkind IN ('r', 'p', 'v', 'm')`).Scan(&objects).Error
+	if err != nil {
+		return fmt.Errorf("failed to inspect database: %w", err)
+	}
+	if objects > 0 {
+		return ErrNotEmpty
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		for i, stmt := range migrate.SplitStatements(string(data)) {
+			if err := tx.Exec(stmt.SQL).Error; err != nil {
+				return fmt.Errorf("%s:%d: statement %d failed: %w", path, stmt.Line, i+1, err)
+			}
+		}
+		return nil
+	})
+}
@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"os"
 	"strings"
+
+	"github.com/Juksefantomet/gecho/internal/templates"
 )
 
 var requiredDirs = []string{
@@ -11,6 +13,7 @@ var requiredDirs = []string{
 	"app/routes",
 	"app/services/database",
 	"db/migrations",
+	"db/seeds",
 }
 
 func RunInit() {
@@ -26,9 +29,11 @@ func RunInit() {
 	writeDotEnvIfMissing()
 	writeMainGoIfMissing(getModuleName("go.mod"))
 	writeDatabaseGoIfMissing()
+	writeMigrationsEmbedIfMissing()
+	writeSeedsKeepIfMissing()
 	writeHelloWorldRouteIfMissing(getModuleName("go.mod"))
 
-	fmt.Println(`
+	fmt.Print(`
 📦 Project ready!
 
 🔧 Next steps:
@@ -77,13 +82,7 @@ func ensureGoModExists() {
 func writeDotEnvIfMissing() {
 	const path = ".env"
 	if _, err := os.Stat(path); os.IsNotExist(err) {
-		content := `# Gecho default database config
-DATABASE_HOST=localhost
-DATABASE_USER=postgres
-DATABASE_PASSWORD=postgres
-DATABASE_NAME=gecho_dev
-DATABASE_PORT=5432
-`
+		content := renderTemplate("init/env.tmpl", "")
 		err := os.WriteFile(path, []byte(content), 0644)
 		if err != nil {
 			fmt.Printf("✗ Failed to write .env: %v\n", err)
@@ -93,6 +92,17 @@ DATABASE_PORT=5432
 	}
 }
 
+// renderTemplate renders the named init template for module, exiting if it
+// fails, e.g. because a project override in .gecho/templates is invalid.
+func renderTemplate(name, module string) string {
+	content, err := templates.Render(name, struct{ Module string }{module})
+	if err != nil {
+		fmt.Printf("✗ Failed to render %s: %v\n", name, err)
+		os.Exit(1)
+	}
+	return content
+}
+
 func getModuleName(path string) string {
 	data, err := os.ReadFile(path)
 	if err != nil {
@@ -121,86 +131,7 @@ func writeMainGoIfMissing(module string) {
 		return
 	}
 
-	content := fmt.Sprintf(`// @title Gecho API
-// @version 1.0
-// @description Auto-generated Echo main.go
-// @host localhost
-// @BasePath /
-// @schemes http
-// @securityDefinitions.apikey BearerAuth
-// @in header
-// @name Authorization
-
-package main
-
-import (
-	"fmt"
-	"log"
-	"net/http"
-	"os"
-	"time"
-
-	"github.com/joho/godotenv"
-	"github.com/labstack/echo/v4"
-	"github.com/labstack/echo/v4/middleware"
-
-	echoSwagger "github.com/swaggo/echo-swagger"
-
-	"%s/app/routes"
-	"%s/app/services/database"
-
-	_ "%s/docs"
-)
-
-var debug = false
-
-func init() {
-	if err := godotenv.Load(); err != nil {
-		log.Println("No .env file found, using defaults")
-	}
-	if os.Getenv("DEBUG") == "true" {
-		debug = true
-	}
-}
-
-// RequestTimeLogger middleware
-func RequestTimeLogger(next echo.HandlerFunc) echo.HandlerFunc {
-	return func(c echo.Context) error {
-		start := time.Now()
-		err := next(c)
-		duration := time.Since(start)
-		log.Printf("Request %%s %%s took %%v", c.Request().Method, c.Request().URL.Path, duration)
-		return err
-	}
-}
-
-func main() {
-	e := echo.New()
-
-	e.Use(RequestTimeLogger)
-
-	database.InitDB()
-
-	e.Use(middleware.CORSWithConfig(middleware.CORSConfig{
-		AllowOrigins: []string{"*"},
-		AllowMethods: []string{echo.GET, echo.POST},
-	}))
-
-	e.GET("/", routes.HelloWorld)
-
-	// Example route:
-	// e.GET("/users", routes.GetUsers)
-
-	e.GET("/swagger/*", echoSwagger.WrapHandler)
-
-	e.GET("/health", func(c echo.Context) error {
-		return c.JSON(http.StatusOK, map[string]string{"message": "Server is healthy"})
-	})
-
-	fmt.Println("Server running on port 3000")
-	e.Logger.Fatal(e.Start(":3000"))
-}
-`, module, module, module)
+	content := renderTemplate("init/main.go.tmpl", module)
 
 	err := os.WriteFile(path, []byte(content), 0644)
 	if err != nil {
@@ -217,60 +148,51 @@ func writeDatabaseGoIfMissing() {
 		return
 	}
 
-	content := `package database
+	content := renderTemplate("init/database.go.tmpl", "")
 
-import (
-	"fmt"
-	"log"
-	"os"
-
-	"gorm.io/driver/postgres"
-	"gorm.io/gorm"
-)
-
-var db *gorm.DB
-
-// InitDB initializes a PostgreSQL connection using env vars
-func InitDB() {
-	var err error
-	dsn := fmt.Sprintf("host=%s user=%s password=%s dbname=%s port=%s sslmode=disable",
-		os.Getenv("DATABASE_HOST"),
-		os.Getenv("DATABASE_USER"),
-		os.Getenv("DATABASE_PASSWORD"),
-		os.Getenv("DATABASE_NAME"),
-		os.Getenv("DATABASE_PORT"),
-	)
-	db, err = gorm.Open(postgres.Open(dsn), &gorm.Config{})
-	if err != nil {
-		log.Fatalf("Failed to connect to database: %%v", err)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		fmt.Printf("✗ Failed to write %s: %v\n", path, err)
+		os.Exit(1)
 	}
-}
 
-// GetDB returns the active DB instance
-func GetDB() *gorm.DB {
-	if db == nil {
-		log.Fatal("Database not initialized. Call InitDB first.")
-	}
-	return db
+	fmt.Println("✓ app/services/database/database.go created.")
 }
 
-// GetPGVersion returns the PostgreSQL version
-func GetPGVersion() string {
-	var version string
-	if err := GetDB().Raw("SELECT version()").Scan(&version).Error; err != nil {
-		log.Println("Failed to get PostgreSQL version:", err)
-		return "Unknown"
+func writeMigrationsEmbedIfMissing() {
+	const path = "db/db.go"
+	if _, err := os.Stat(path); err == nil {
+		return
 	}
-	return version
-}
-`
+
+	content := renderTemplate("init/db.go.tmpl", "")
 
 	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
 		fmt.Printf("✗ Failed to write %s: %v\n", path, err)
 		os.Exit(1)
 	}
 
-	fmt.Println("✓ app/services/database/database.go created.")
+	// An empty directory cannot be embedded, so keep a placeholder in it.
+	const keep = "db/migrations/.gitkeep"
+	if _, err := os.Stat(keep); os.IsNotExist(err) {
+		if err := os.WriteFile(keep, nil, 0644); err != nil {
+			fmt.Printf("✗ Failed to write %s: %v\n", keep, err)
+			os.Exit(1)
+		}
+	}
+
+	fmt.Println("✓ db/db.go created.")
+}
+
+// writeSeedsKeepIfMissing keeps the otherwise empty db/seeds in git.
+func writeSeedsKeepIfMissing() {
+	const keep = "db/seeds/.gitkeep"
+	if _, err := os.Stat(keep); err == nil {
+		return
+	}
+	if err := os.WriteFile(keep, nil, 0644); err != nil {
+		fmt.Printf("✗ Failed to write %s: %v\n", keep, err)
+		os.Exit(1)
+	}
 }
 
 func writeHelloWorldRouteIfMissing(module string) {
@@ -279,19 +201,7 @@ func writeHelloWorldRouteIfMissing(module string) {
 		return
 	}
 
-	content := fmt.Sprintf(`package routes
-
-import (
-	"net/http"
-
-	"github.com/labstack/echo/v4"
-)
-
-// HelloWorld returns a simple status message
-func HelloWorld(c echo.Context) error {
-	return c.JSON(http.StatusOK, map[string]string{"message": "Hello from Gecho"})
-}
-`)
+	content := renderTemplate("init/helloWorld.go.tmpl", module)
 	err := os.WriteFile(path, []byte(content), 0644)
 	if err != nil {
 		fmt.Printf("✗ Failed to write %s: %v\n", path, err)
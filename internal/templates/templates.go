@@ -0,0 +1,123 @@
+// Package templates renders the files gecho init and gecho scaffold
+// generate. The built-in templates are embedded from defaults/; a project
+// overrides any of them by putting a file with the same name under
+// .gecho/templates, e.g. .gecho/templates/scaffold/model.go.tmpl.
+//
+// Templates use text/template. Output of templates ending in .go.tmpl is
+// gofmt'ed, so they need not get imports or alignment exactly right.
+//
+// init/ templates are executed with:
+//
+//	.Module      module path from go.mod, e.g. example.com/shop
+//
+// scaffold/ templates are executed with a scaffold.Data:
+//
+//	.Module      module path from go.mod
+//	.StructName  model type, e.g. OrderItem
+//	.PluralName  plural used in function names, e.g. OrderItems
+//	.TableName   table created by the migration, e.g. order_items
+//	.URLPath     path the routes are registered under, e.g. order_items
+//	.HumanName   name used in messages and docs, e.g. order item
+//	.IDFunc      name of the :id parameter parser, e.g. orderItemID
+//	.Width       length of the longest column name
+//	.Fields      the fields given to scaffold, each with
+//	               .Name (column), .Type, .Unique, .Index, .Null, .Default,
+//	               .GoName, .GoType, .Pointer, .RequestType, .PatchType,
+//	               .Tags, .RequestTags, .Required and .Column width (the
+//	               migration's column definition);
+//	               references fields also have .References (post),
+//	               .RefTable (posts), .OnDelete, .RefStruct (Post) and
+//	               .RefVar (postID)
+//	.References  the references fields
+//	.HasType t…  whether any field has one of the given types
+//	.HasDefault  whether any field has a default
+package templates
+
+import (
+	"embed"
+	"errors"
+	"fmt"
+	"go/format"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"text/template"
+)
+
+// OverrideDir holds project templates that replace the built-in ones.
+const OverrideDir = ".gecho/templates"
+
+//go:embed defaults
+var defaults embed.FS
+
+// Names lists the built-in templates, e.g. scaffold/model.go.tmpl.
+func Names() []string {
+	var names []string
+	_ = fs.WalkDir(defaults, "defaults", func(p string, d fs.DirEntry, err error) error {
+		if err == nil && !d.IsDir() {
+			names = append(names, strings.TrimPrefix(p, "defaults/"))
+		}
+		return err
+	})
+	return names
+}
+
+// Render executes the named template with data, preferring the project's
+// copy in OverrideDir over the built-in one.
+func Render(name string, data any) (string, error) {
+	file := filepath.Join(OverrideDir, filepath.FromSlash(name))
+	src, err := os.ReadFile(file)
+	if errors.Is(err, fs.ErrNotExist) {
+		file = name
+		src, err = defaults.ReadFile(path.Join("defaults", name))
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read template %s: %w", name, err)
+	}
+
+	tmpl, err := template.New(file).Option("missingkey=error").Parse(string(src))
+	if err != nil {
+		return "", err
+	}
+	var out strings.Builder
+	if err := tmpl.Execute(&out, data); err != nil {
+		return "", err
+	}
+
+	if !strings.HasSuffix(name, ".go.tmpl") {
+		return out.String(), nil
+	}
+	// Unparsable output is returned as is, so the problem shows up where
+	// the generated file is compiled.
+	formatted, err := format.Source([]byte(out.String()))
+	if err != nil {
+		return out.String(), nil
+	}
+	return string(formatted), nil
+}
+
+// Eject copies the built-in templates into OverrideDir for editing. Files
+// already there are kept unless force is set. It returns the files written.
+func Eject(force bool) ([]string, error) {
+	var written []string
+	for _, name := range Names() {
+		file := filepath.Join(OverrideDir, filepath.FromSlash(name))
+		if _, err := os.Stat(file); err == nil && !force {
+			continue
+		}
+		src, err := defaults.ReadFile(path.Join("defaults", name))
+		if err != nil {
+			return written, err
+		}
+		if err := os.MkdirAll(filepath.Dir(file), os.ModePerm); err != nil {
+			return written, fmt.Errorf("failed to create %s: %w", filepath.Dir(file), err)
+		}
+		if err := os.WriteFile(file, src, 0644); err != nil {
+			return written, fmt.Errorf("failed to write %s: %w", file, err)
+		}
+		written = append(written, file)
+	}
+	return written, nil
+}
@@ -1,9 +1,14 @@
 package database
 
 import (
+	"errors"
 	"fmt"
-	"log"
+	"net/url"
 	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
 
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
@@ -11,28 +16,203 @@ import (
 
 var db *gorm.DB
 
+// ErrNotInitialized is returned by GetDB before InitDB has succeeded.
+var ErrNotInitialized = errors.New("database is not initialized, call InitDB first")
+
 // InitDB reads env vars and initializes the GORM DB connection
-func InitDB() {
-	var err error
-
-	dsn := fmt.Sprintf("host=%s user=%s password=%s dbname=%s port=%s sslmode=disable",
-		os.Getenv("DATABASE_HOST"),
-		os.Getenv("DATABASE_USER"),
-		os.Getenv("DATABASE_PASSWORD"),
-		os.Getenv("DATABASE_NAME"),
-		os.Getenv("DATABASE_PORT"),
-	)
-
-	db, err = gorm.Open(postgres.Open(dsn), &gorm.Config{})
-	if err != nil {
-		log.Fatalf("Failed to connect to database: %v", err)
-	}
+//
+// DATABASE_URL takes precedence over DATABASE_HOST, _PORT, _USER, _PASSWORD
+// and _NAME. DATABASE_SSLMODE, DATABASE_SSLROOTCERT, DATABASE_SCHEMA (the
+// search_path) and DATABASE_CONNECT_TIMEOUT (seconds) apply to either form.
+// DATABASE_MAX_OPEN_CONNS, DATABASE_MAX_IDLE_CONNS and
+// DATABASE_CONN_MAX_LIFETIME (a duration such as 30m) size the pool.
+func InitDB() error {
+	return open(nil)
+}
+
+// InitReadOnlyDB initializes a connection whose transactions are read-only,
+// so a dry run cannot modify the database even by accident.
+func InitReadOnlyDB() error {
+	return open(map[string]string{"default_transaction_read_only": "on"})
 }
 
 // GetDB returns the active GORM DB connection
-func GetDB() *gorm.DB {
+func GetDB() (*gorm.DB, error) {
 	if db == nil {
-		log.Fatal("Database is not initialized. Call InitDB() first.")
+		return nil, ErrNotInitialized
+	}
+	return db, nil
+}
+
+// Name returns the configured database, the path of DATABASE_URL or else
+// DATABASE_NAME.
+func Name() (string, error) {
+	if rawURL := os.Getenv("DATABASE_URL"); rawURL != "" {
+		u, err := url.Parse(rawURL)
+		if err != nil {
+			return "", fmt.Errorf("invalid DATABASE_URL: %w", err)
+		}
+		if name := strings.TrimPrefix(u.Path, "/"); name != "" {
+			return name, nil
+		}
+		return "", errors.New("DATABASE_URL does not name a database")
+	}
+	if name := os.Getenv("DATABASE_NAME"); name != "" {
+		return name, nil
+	}
+	return "", errors.New("DATABASE_NAME is not set")
+}
+
+// Schema returns the schema migrations and the migrations table live in,
+// or "" for the server's default search_path.
+func Schema() string {
+	return os.Getenv("DATABASE_SCHEMA")
+}
+
+// EnsureSchema creates the configured DATABASE_SCHEMA if it is missing.
+func EnsureSchema() error {
+	schema := Schema()
+	if schema == "" {
+		return nil
+	}
+	conn, err := GetDB()
+	if err != nil {
+		return err
+	}
+	if err := conn.Exec("CREATE SCHEMA IF NOT EXISTS " + quoteIdent(schema)).Error; err != nil {
+		return fmt.Errorf("failed to create schema %s: %w", schema, err)
+	}
+	return nil
+}
+
+// dsn builds the connection string from the environment, adding params as
+// extra connection parameters. A dbname param replaces the configured
+// database.
+func dsn(params map[string]string) (string, error) {
+	settings := map[string]string{
+		"sslmode":         os.Getenv("DATABASE_SSLMODE"),
+		"sslrootcert":     os.Getenv("DATABASE_SSLROOTCERT"),
+		"search_path":     Schema(),
+		"connect_timeout": os.Getenv("DATABASE_CONNECT_TIMEOUT"),
+	}
+	for k, v := range params {
+		settings[k] = v
+	}
+
+	if rawURL := os.Getenv("DATABASE_URL"); rawURL != "" {
+		u, err := url.Parse(rawURL)
+		if err != nil {
+			return "", fmt.Errorf("invalid DATABASE_URL: %w", err)
+		}
+		query := u.Query()
+		for k, v := range settings {
+			switch {
+			case v == "":
+			case k == "dbname":
+				u.Path = "/" + v
+			default:
+				query.Set(k, v)
+			}
+		}
+		u.RawQuery = query.Encode()
+		return u.String(), nil
+	}
+
+	if settings["sslmode"] == "" {
+		settings["sslmode"] = "disable"
+	}
+	settings["host"] = os.Getenv("DATABASE_HOST")
+	settings["port"] = os.Getenv("DATABASE_PORT")
+	settings["user"] = os.Getenv("DATABASE_USER")
+	settings["password"] = os.Getenv("DATABASE_PASSWORD")
+	if settings["dbname"] == "" {
+		settings["dbname"] = os.Getenv("DATABASE_NAME")
+	}
+
+	var parts []string
+	for _, k := range sortedKeys(settings) {
+		if settings[k] != "" {
+			parts = append(parts, k+"="+quoteValue(settings[k]))
+		}
+	}
+	return strings.Join(parts, " "), nil
+}
+
+func open(params map[string]string) error {
+	conn, err := connect(params)
+	if err != nil {
+		return err
+	}
+	if err := configurePool(conn); err != nil {
+		return err
+	}
+	db = conn
+	return nil
+}
+
+func connect(params map[string]string) (*gorm.DB, error) {
+	connStr, err := dsn(params)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := gorm.Open(postgres.Open(connStr), &gorm.Config{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to database: %w", err)
+	}
+	return conn, nil
+}
+
+// configurePool applies the DATABASE_MAX_OPEN_CONNS, DATABASE_MAX_IDLE_CONNS
+// and DATABASE_CONN_MAX_LIFETIME settings.
+func configurePool(conn *gorm.DB) error {
+	sqlDB, err := conn.DB()
+	if err != nil {
+		return fmt.Errorf("failed to access database connection: %w", err)
+	}
+
+	if v := os.Getenv("DATABASE_MAX_OPEN_CONNS"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return fmt.Errorf("invalid DATABASE_MAX_OPEN_CONNS %q: %w", v, err)
+		}
+		sqlDB.SetMaxOpenConns(n)
+	}
+	if v := os.Getenv("DATABASE_MAX_IDLE_CONNS"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return fmt.Errorf("invalid DATABASE_MAX_IDLE_CONNS %q: %w", v, err)
+		}
+		sqlDB.SetMaxIdleConns(n)
+	}
+	if v := os.Getenv("DATABASE_CONN_MAX_LIFETIME"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return fmt.Errorf("invalid DATABASE_CONN_MAX_LIFETIME %q: %w", v, err)
+		}
+		sqlDB.SetConnMaxLifetime(d)
+	}
+	return nil
+}
+
+// quoteValue quotes a keyword/value connection string value when needed.
+func quoteValue(v string) string {
+	if !strings.ContainsAny(v, ` '\`) {
+		return v
+	}
+	v = strings.ReplaceAll(v, `\`, `\\`)
+	v = strings.ReplaceAll(v, `'`, `\'`)
+	return "'" + v + "'"
+}
+
+func quoteIdent(s string) string {
+	return `"` + strings.ReplaceAll(s, `"`, `""`) + `"`
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
 	}
-	return db
+	sort.Strings(keys)
+	return keys
 }
@@ -0,0 +1,75 @@
+package database
+
+import (
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+// CreateDatabase creates the configured database through the server's
+// postgres maintenance database. It reports false if it already existed.
+func CreateDatabase() (bool, error) {
+	name, err := Name()
+	if err != nil {
+		return false, err
+	}
+	created := false
+	err = withMaintenance(func(conn *gorm.DB) error {
+		exists, err := databaseExists(conn, name)
+		if err != nil || exists {
+			return err
+		}
+		if err := conn.Exec("CREATE DATABASE " + quoteIdent(name)).Error; err != nil {
+			return fmt.Errorf("failed to create database %s: %w", name, err)
+		}
+		created = true
+		return nil
+	})
+	return created, err
+}
+
+// DropDatabase drops the configured database through the server's postgres
+// maintenance database. It reports false if there was nothing to drop.
+func DropDatabase() (bool, error) {
+	name, err := Name()
+	if err != nil {
+		return false, err
+	}
+	dropped := false
+	err = withMaintenance(func(conn *gorm.DB) error {
+		exists, err := databaseExists(conn, name)
+		if err != nil || !exists {
+			return err
+		}
+		if err := conn.Exec("DROP DATABASE " + quoteIdent(name)).Error; err != nil {
+			return fmt.Errorf("failed to drop database %s (is it still in use?): %w", name, err)
+		}
+		dropped = true
+		return nil
+	})
+	return dropped, err
+}
+
+// withMaintenance runs fn on a short-lived connection to the postgres
+// database of the configured server, separate from the one GetDB returns.
+func withMaintenance(fn func(conn *gorm.DB) error) error {
+	conn, err := connect(map[string]string{"dbname": "postgres"})
+	if err != nil {
+		return err
+	}
+	sqlDB, err := conn.DB()
+	if err != nil {
+		return fmt.Errorf("failed to access database connection: %w", err)
+	}
+	defer sqlDB.Close()
+	return fn(conn)
+}
+
+func databaseExists(conn *gorm.DB, name string) (bool, error) {
+	var exists bool
+	err := conn.Raw("SELECT EXISTS (SELECT 1 FROM pg_database WHERE datname = ?)", name).Scan(&exists).Error
+	if err != nil {
+		return false, fmt.Errorf("failed to look up database %s: %w", name, err)
+	}
+	return exists, nil
+}
@@ -0,0 +1,125 @@
+package migrate
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+)
+
+// checksums returns the SHA-256 of a migration's up and down files. A
+// missing file yields an empty checksum; Go migrations only have an up file.
+func (m *Migrator) checksums(upName string) (up, down string, err error) {
+	if up, err = m.fileChecksum(upName); err != nil {
+		return "", "", err
+	}
+	if isGoMigration(upName) {
+		return up, "", nil
+	}
+
+	downFile, err := downName(upName)
+	if err != nil {
+		return "", "", err
+	}
+	if down, err = m.fileChecksum(downFile); err != nil {
+		return "", "", err
+	}
+	return up, down, nil
+}
+
+func (m *Migrator) fileChecksum(name string) (string, error) {
+	data, err := fs.ReadFile(m.fsys, name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s: %w", name, err)
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// hasDrifted reports whether a migration's files changed since it was
+// applied. Orphaned records and records predating checksum tracking never
+// drift.
+func (m *Migrator) hasDrifted(applied Migration) (bool, error) {
+	if applied.UpChecksum == "" || !m.fileExists(applied.Name) {
+		return false, nil
+	}
+	up, down, err := m.checksums(applied.Name)
+	if err != nil {
+		return false, err
+	}
+	return up != applied.UpChecksum || down != applied.DownChecksum, nil
+}
+
+// verifyChecksums fails with ErrDrift if any applied migration has
+// drifted, unless AllowDrift is set.
+func (m *Migrator) verifyChecksums(applied []Migration) error {
+	var drifted []string
+	for _, a := range applied {
+		changed, err := m.hasDrifted(a)
+		if err != nil {
+			return err
+		}
+		if changed {
+			drifted = append(drifted, a.Name)
+		}
+	}
+	if len(drifted) == 0 {
+		return nil
+	}
+
+	err := &ErrDrift{Names: drifted}
+	if m.opts.AllowDrift {
+		m.opts.Logger.Printf("Warning: %v", err)
+		return nil
+	}
+	return err
+}
+
+// Repair re-records the checksums of every applied migration whose files
+// are still present, accepting intentional edits. It returns how many
+// records changed.
+func (m *Migrator) Repair() (int, error) {
+	repaired := 0
+	err := m.withLock(func() error {
+		if err := m.table().AutoMigrate(&Migration{}); err != nil {
+			return fmt.Errorf("failed to create %s table: %w", m.opts.TableName, err)
+		}
+
+		var applied []Migration
+		if err := m.table().Find(&applied).Error; err != nil {
+			return fmt.Errorf("failed to read %s table: %w", m.opts.TableName, err)
+		}
+
+		for _, a := range applied {
+			if !m.fileExists(a.Name) {
+				continue
+			}
+			up, down, err := m.checksums(a.Name)
+			if err != nil {
+				return err
+			}
+			if up == a.UpChecksum && down == a.DownChecksum {
+				continue
+			}
+
+			err = m.table().Where("id = ?", a.ID).
+				Updates(map[string]interface{}{"up_checksum": up, "down_checksum": down}).Error
+			if err != nil {
+				return fmt.Errorf("failed to update checksum for %s: %w", a.Name, err)
+			}
+			m.opts.Logger.Printf("Recorded checksum for %s", a.Name)
+			repaired++
+		}
+		return nil
+	})
+	return repaired, err
+}
+
+func (m *Migrator) fileExists(name string) bool {
+	_, err := fs.Stat(m.fsys, name)
+	return err == nil
+}
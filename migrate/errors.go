@@ -0,0 +1,108 @@
+package migrate
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// ErrMigrationFailed reports a migration that could not be applied or
+// rolled back. Nothing from it was committed unless it is no-transaction.
+type ErrMigrationFailed struct {
+	Name      string
+	File      string // .up.sql or .down.sql that failed, "" for Go migrations
+	Index     int    // 1-based index of the failing statement in File
+	Line      int    // line of File the failing statement starts on
+	Statement string
+	Cause     error
+}
+
+func (e *ErrMigrationFailed) Error() string {
+	if e.File == "" {
+		return fmt.Sprintf("migration %s failed: %v", e.Name, e.Cause)
+	}
+
+	line, excerpt := e.location()
+	var b strings.Builder
+	fmt.Fprintf(&b, "migration %s failed at statement %d (%s:%d): %v", e.Name, e.Index, e.File, line, e.Cause)
+
+	var pgErr *pgconn.PgError
+	if errors.As(e.Cause, &pgErr) {
+		if pgErr.Detail != "" {
+			fmt.Fprintf(&b, "\n  detail: %s", pgErr.Detail)
+		}
+		if pgErr.Hint != "" {
+			fmt.Fprintf(&b, "\n  hint: %s", pgErr.Hint)
+		}
+	}
+	fmt.Fprintf(&b, "\n  sql: %s", excerpt)
+	return b.String()
+}
+
+// location returns the file line Postgres reported the error on, falling
+// back to the statement's first line, and a one-line excerpt of it.
+func (e *ErrMigrationFailed) location() (int, string) {
+	lines := strings.Split(e.Statement, "\n")
+	offset := 0
+
+	var pgErr *pgconn.PgError
+	if errors.As(e.Cause, &pgErr) && pgErr.Position > 0 {
+		// Position counts characters from 1 within the statement.
+		runes := []rune(e.Statement)
+		if pos := int(pgErr.Position) - 1; pos < len(runes) {
+			offset = strings.Count(string(runes[:pos]), "\n")
+		}
+	}
+
+	excerpt := strings.TrimSpace(lines[offset])
+	if len(excerpt) > 120 {
+		excerpt = excerpt[:120] + "…"
+	} else if offset == 0 && len(lines) > 1 {
+		excerpt += " …"
+	}
+	return e.Line + offset, excerpt
+}
+
+func (e *ErrMigrationFailed) Unwrap() error { return e.Cause }
+
+// ErrMissingDown reports a rollback of a migration without a .down.sql.
+type ErrMissingDown struct {
+	Name string
+}
+
+func (e *ErrMissingDown) Error() string {
+	return fmt.Sprintf("missing .down.sql for %s", e.Name)
+}
+
+// ErrDrift reports applied migrations whose files changed after they ran.
+type ErrDrift struct {
+	Names []string
+}
+
+func (e *ErrDrift) Error() string {
+	return fmt.Sprintf("applied migrations were modified after being applied: %s", strings.Join(e.Names, ", "))
+}
+
+// ErrLockTimeout reports that another process held the migration lock for
+// longer than the configured timeout.
+type ErrLockTimeout struct {
+	Table   string
+	Timeout time.Duration
+	PID     string
+}
+
+func (e *ErrLockTimeout) Error() string {
+	return fmt.Sprintf("timed out after %s waiting for migration lock on %q (held by PID %s)", e.Timeout, e.Table, e.PID)
+}
+
+// ErrUnknownVersion reports a target version no migration file or record has.
+type ErrUnknownVersion struct {
+	Version string
+}
+
+func (e *ErrUnknownVersion) Error() string {
+	return fmt.Sprintf("no migration with version %s found", e.Version)
+}
@@ -0,0 +1,92 @@
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"time"
+)
+
+// DefaultLockTimeout is how long a migration run waits for another process
+// to release the migration lock before giving up.
+const DefaultLockTimeout = time.Minute
+
+const lockPollInterval = 500 * time.Millisecond
+
+// withLock runs fn while holding a session-level Postgres advisory lock on
+// a dedicated connection, so concurrent gecho runs apply migrations one at
+// a time. The lock is keyed on the migrations table name so every process
+// sharing that table agrees on it.
+func (m *Migrator) withLock(fn func() error) error {
+	sqlDB, err := m.db.DB()
+	if err != nil {
+		return fmt.Errorf("failed to access database connection: %w", err)
+	}
+	if sqlDB.Stats().MaxOpenConnections == 1 {
+		return errors.New("migrations need a pool of at least 2 connections: one holds the migration lock")
+	}
+
+	ctx := context.Background()
+	conn, err := sqlDB.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to open connection for migration lock: %w", err)
+	}
+	defer conn.Close()
+
+	key := lockKey(m.opts.TableName)
+	deadline := time.Now().Add(m.opts.LockTimeout)
+	waiting := false
+	for {
+		var locked bool
+		if err := conn.QueryRowContext(ctx, "SELECT pg_try_advisory_lock($1)", key).Scan(&locked); err != nil {
+			return fmt.Errorf("failed to acquire migration lock: %w", err)
+		}
+		if locked {
+			break
+		}
+
+		holder := lockHolder(ctx, conn, key)
+		if time.Now().After(deadline) {
+			return &ErrLockTimeout{Table: m.opts.TableName, Timeout: m.opts.LockTimeout, PID: holder}
+		}
+		if !waiting {
+			m.opts.Logger.Printf("Waiting for migration lock held by PID %s...", holder)
+			waiting = true
+		}
+		time.Sleep(lockPollInterval)
+	}
+
+	defer func() {
+		if _, err := conn.ExecContext(ctx, "SELECT pg_advisory_unlock($1)", key); err != nil {
+			m.opts.Logger.Printf("Failed to release migration lock: %v", err)
+		}
+	}()
+	return fn()
+}
+
+// lockHolder looks up the PID of the backend holding the advisory lock, or
+// "unknown" if it has already been released.
+func lockHolder(ctx context.Context, conn *sql.Conn, key int64) string {
+	// A bigint advisory key is split across classid (high) and objid (low).
+	var pid string
+	err := conn.QueryRowContext(ctx, `
+		SELECT pid::text FROM pg_locks
+		WHERE locktype = 'advisory' AND granted
+		  AND database = (SELECT oid FROM pg_database WHERE datname = current_database())
+		  AND classid::bigint = $1 AND objid::bigint = $2 AND objsubid = 1
+		LIMIT 1`,
+		int64(uint64(key)>>32), int64(uint64(key)&0xffffffff),
+	).Scan(&pid)
+	if err != nil {
+		return "unknown"
+	}
+	return pid
+}
+
+func lockKey(table string) int64 {
+	h := fnv.New64a()
+	h.Write([]byte("gecho:" + table))
+	return int64(h.Sum64())
+}
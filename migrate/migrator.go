@@ -0,0 +1,481 @@
+// Package migrate applies and rolls back timestamped SQL migrations against
+// PostgreSQL. It is the engine behind `gecho migrate` and can be embedded in
+// applications to migrate at startup:
+//
+//	//go:embed db/migrations
+//	var migrations embed.FS
+//
+//	sub, _ := fs.Sub(migrations, "db/migrations")
+//	err := migrate.New(db, sub, migrate.Options{}).Up(0)
+package migrate
+
+import (
+	"bufio"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+// noTransactionMarker in a migration's header opts it out of the wrapping
+// transaction, for statements Postgres refuses to run inside one
+// (e.g. CREATE INDEX CONCURRENTLY).
+const noTransactionMarker = "-- gecho:no-transaction"
+
+// DefaultTableName is the table tracking applied migrations.
+const DefaultTableName = "migrations"
+
+// Migration represents a migration record in the database
+type Migration struct {
+	ID           uint   `gorm:"primaryKey"`
+	Name         string `gorm:"unique"`
+	UpChecksum   string
+	DownChecksum string
+	Batch        int `gorm:"index"` // migrations applied by the same run share a batch
+	AppliedAt    time.Time
+}
+
+// Logger receives progress messages. *log.Logger satisfies it.
+type Logger interface {
+	Printf(format string, v ...any)
+}
+
+// Options configures a Migrator. The zero value is ready to use.
+type Options struct {
+	// TableName is the table tracking applied migrations; it also keys the
+	// advisory lock. Defaults to DefaultTableName.
+	TableName string
+	// Logger receives progress messages. Defaults to log.Default().
+	Logger Logger
+	// LockTimeout is how long to wait for another process to release the
+	// migration lock. Defaults to DefaultLockTimeout.
+	LockTimeout time.Duration
+	// AllowDrift lets runs continue when applied migrations were modified
+	// after they ran.
+	AllowDrift bool
+}
+
+// Migrator applies and rolls back the .up.sql/.down.sql files of a file
+// system, interleaved with registered Go migrations, tracking them in the
+// migrations table.
+type Migrator struct {
+	db   *gorm.DB
+	fsys fs.FS
+	opts Options
+}
+
+// New returns a Migrator reading migration files from the root of fsys,
+// e.g. os.DirFS("db/migrations") or a sub-tree of an embed.FS.
+func New(db *gorm.DB, fsys fs.FS, opts Options) *Migrator {
+	if opts.TableName == "" {
+		opts.TableName = DefaultTableName
+	}
+	if opts.Logger == nil {
+		opts.Logger = log.Default()
+	}
+	if opts.LockTimeout == 0 {
+		opts.LockTimeout = DefaultLockTimeout
+	}
+	return &Migrator{db: db, fsys: fsys, opts: opts}
+}
+
+// NewFromSQL is New for applications that manage a *sql.DB connected to
+// PostgreSQL rather than a *gorm.DB.
+func NewFromSQL(sqlDB *sql.DB, fsys fs.FS, opts Options) (*Migrator, error) {
+	db, err := gorm.Open(postgres.New(postgres.Config{Conn: sqlDB}), &gorm.Config{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to wrap database connection: %w", err)
+	}
+	return New(db, fsys, opts), nil
+}
+
+// Up applies the next n pending migrations in filename order, or all of
+// them when n is 0.
+func (m *Migrator) Up(n int) error {
+	return m.withLock(func() error {
+		applied, err := m.loadApplied()
+		if err != nil {
+			return err
+		}
+		pending, err := m.migrationsToApply(applied, n)
+		if err != nil {
+			return err
+		}
+
+		batch := nextBatch(applied)
+		for _, name := range pending {
+			if err := m.applyMigration(name, batch); err != nil {
+				return err
+			}
+		}
+
+		m.opts.Logger.Printf("All migrations applied.")
+		return nil
+	})
+}
+
+// Down undoes the last n applied migrations in reverse filename order, or
+// every migration of the last batch when n is 0.
+func (m *Migrator) Down(n int) error {
+	return m.withLock(func() error {
+		applied, err := m.loadApplied()
+		if err != nil {
+			return err
+		}
+
+		targets := migrationsToRollback(applied, n)
+		if len(targets) == 0 {
+			m.opts.Logger.Printf("No applied migrations to roll back.")
+			return nil
+		}
+
+		for _, target := range targets {
+			if err := m.rollbackMigration(target); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// To applies or rolls back migrations until the schema matches version:
+// every migration up to and including it applied, every later one rolled
+// back. A version of "0" rolls back everything.
+func (m *Migrator) To(version string) error {
+	return m.withLock(func() error {
+		applied, err := m.loadApplied()
+		if err != nil {
+			return err
+		}
+		pending, err := m.pendingMigrations(applied)
+		if err != nil {
+			return err
+		}
+
+		if version != "0" && !knownVersion(version, applied, pending) {
+			return &ErrUnknownVersion{Version: version}
+		}
+
+		for i := len(applied) - 1; i >= 0; i-- {
+			if version == "0" || migrationVersion(applied[i].Name) > version {
+				if err := m.rollbackMigration(applied[i]); err != nil {
+					return err
+				}
+			}
+		}
+
+		batch := nextBatch(applied)
+		for _, name := range pending {
+			if migrationVersion(name) <= version {
+				if err := m.applyMigration(name, batch); err != nil {
+					return err
+				}
+			}
+		}
+
+		m.opts.Logger.Printf("Schema is at version %s.", version)
+		return nil
+	})
+}
+
+// table scopes a query to the migrations table.
+func (m *Migrator) table() *gorm.DB {
+	return m.db.Table(m.opts.TableName)
+}
+
+// loadApplied ensures the migrations table exists and returns its rows in
+// filename order, failing if any of them drifted.
+func (m *Migrator) loadApplied() ([]Migration, error) {
+	if err := m.table().AutoMigrate(&Migration{}); err != nil {
+		return nil, fmt.Errorf("failed to create %s table: %w", m.opts.TableName, err)
+	}
+
+	var applied []Migration
+	if err := m.table().Order("name").Find(&applied).Error; err != nil {
+		return nil, fmt.Errorf("failed to read %s table: %w", m.opts.TableName, err)
+	}
+	if err := m.verifyChecksums(applied); err != nil {
+		return nil, err
+	}
+	return applied, nil
+}
+
+// pendingMigrations lists the migrations not yet recorded in applied, in
+// filename order.
+func (m *Migrator) pendingMigrations(applied []Migration) ([]string, error) {
+	appliedMap := make(map[string]bool)
+	for _, a := range applied {
+		appliedMap[a.Name] = true
+	}
+
+	files, err := m.upFiles()
+	if err != nil {
+		return nil, err
+	}
+
+	var pending []string
+	for _, name := range files {
+		if !appliedMap[name] {
+			pending = append(pending, name)
+		}
+	}
+	return pending, nil
+}
+
+// upFiles lists every migration in filename order: the .up.sql files plus
+// the registered Go migrations, named <timestamp>_<name>.go.
+func (m *Migrator) upFiles() ([]string, error) {
+	if err := checkRegistered(m.fsys); err != nil {
+		return nil, err
+	}
+
+	files, err := fs.Glob(m.fsys, "*.up.sql")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migration files: %w", err)
+	}
+	files = append(files, registeredNames()...)
+	sort.Strings(files)
+	return files, nil
+}
+
+// migrationsToApply returns the next n pending migrations, or all of them
+// when n is 0.
+func (m *Migrator) migrationsToApply(applied []Migration, n int) ([]string, error) {
+	pending, err := m.pendingMigrations(applied)
+	if err != nil {
+		return nil, err
+	}
+	if n > 0 && n < len(pending) {
+		pending = pending[:n]
+	}
+	return pending, nil
+}
+
+// migrationsToRollback returns the last n applied migrations, or the last
+// batch when n is 0, latest first. applied must be in filename order.
+func migrationsToRollback(applied []Migration, n int) []Migration {
+	last := nextBatch(applied) - 1
+	if n == 0 && last == 0 {
+		// Recorded before batches existed; fall back to a single step.
+		n = 1
+	}
+
+	var targets []Migration
+	for i := len(applied) - 1; i >= 0; i-- {
+		if n > 0 && len(targets) == n {
+			break
+		}
+		if n == 0 && applied[i].Batch != last {
+			continue
+		}
+		targets = append(targets, applied[i])
+	}
+	return targets
+}
+
+// nextBatch returns the batch number for a new migration run.
+func nextBatch(applied []Migration) int {
+	last := 0
+	for _, m := range applied {
+		if m.Batch > last {
+			last = m.Batch
+		}
+	}
+	return last + 1
+}
+
+func (m *Migrator) applyMigration(name string, batch int) error {
+	body, noTransaction, err := m.upBody(name)
+	if err != nil {
+		return err
+	}
+
+	upChecksum, downChecksum, err := m.checksums(name)
+	if err != nil {
+		return err
+	}
+	record := Migration{
+		Name:         name,
+		UpChecksum:   upChecksum,
+		DownChecksum: downChecksum,
+		Batch:        batch,
+		AppliedAt:    time.Now(),
+	}
+
+	m.opts.Logger.Printf("Applying migration: %s", name)
+	return m.execMigration(name, noTransaction, body, func(tx *gorm.DB) error {
+		return tx.Table(m.opts.TableName).Create(&record).Error
+	})
+}
+
+func (m *Migrator) rollbackMigration(target Migration) error {
+	body, noTransaction, err := m.downBody(target.Name)
+	if err != nil {
+		return err
+	}
+
+	m.opts.Logger.Printf("Rolling back: %s", target.Name)
+	err = m.execMigration(target.Name, noTransaction, body, func(tx *gorm.DB) error {
+		return tx.Table(m.opts.TableName).Delete(&target).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	m.opts.Logger.Printf("Rollback complete for %s.", target.Name)
+	return nil
+}
+
+// upBody returns the step applying the migration recorded as name, and
+// whether it must run outside a transaction.
+func (m *Migrator) upBody(name string) (func(tx *gorm.DB) error, bool, error) {
+	if isGoMigration(name) {
+		g, ok := lookupGo(name)
+		if !ok {
+			return nil, false, fmt.Errorf("go migration %s is not registered", name)
+		}
+		return goBody(name, g.up), false, nil
+	}
+
+	sql, err := fs.ReadFile(m.fsys, name)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to read %s: %w", name, err)
+	}
+	return sqlBody(name, name, string(sql)), !useTransaction(string(sql)), nil
+}
+
+// downBody returns the step rolling back the migration recorded as name,
+// and whether it must run outside a transaction.
+func (m *Migrator) downBody(name string) (func(tx *gorm.DB) error, bool, error) {
+	if isGoMigration(name) {
+		g, ok := lookupGo(name)
+		if !ok || g.down == nil {
+			return nil, false, &ErrMissingDown{Name: name}
+		}
+		return goBody(name, g.down), false, nil
+	}
+
+	down, err := downName(name)
+	if err != nil {
+		return nil, false, err
+	}
+	sql, err := fs.ReadFile(m.fsys, down)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, false, &ErrMissingDown{Name: name}
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to read %s: %w", down, err)
+	}
+	return sqlBody(name, down, string(sql)), !useTransaction(string(sql)), nil
+}
+
+// sqlBody executes the statements of file one at a time, so a failure can
+// be traced to its statement and line.
+func sqlBody(name, file, sql string) func(tx *gorm.DB) error {
+	return func(tx *gorm.DB) error {
+		for i, stmt := range SplitStatements(sql) {
+			if err := tx.Exec(stmt.SQL).Error; err != nil {
+				return &ErrMigrationFailed{
+					Name:      name,
+					File:      file,
+					Index:     i + 1,
+					Line:      stmt.Line,
+					Statement: stmt.SQL,
+					Cause:     err,
+				}
+			}
+		}
+		return nil
+	}
+}
+
+func goBody(name string, fn GoMigrationFunc) func(tx *gorm.DB) error {
+	return func(tx *gorm.DB) error {
+		if err := fn(tx); err != nil {
+			return &ErrMigrationFailed{Name: name, Cause: err}
+		}
+		return nil
+	}
+}
+
+// execMigration runs body and then record in a single transaction so a
+// migration and its tracking row are committed together. Migrations marked
+// no-transaction run outside one, but still on a single connection, so a
+// SET in the file applies to the statements after it; RESET ALL then keeps
+// those settings from leaking into the pool.
+func (m *Migrator) execMigration(name string, noTransaction bool, body, record func(tx *gorm.DB) error) error {
+	run := func(tx *gorm.DB) error {
+		if err := body(tx); err != nil {
+			return err
+		}
+		if err := record(tx); err != nil {
+			return fmt.Errorf("failed to update %s for %s: %w", m.opts.TableName, name, err)
+		}
+		return nil
+	}
+
+	if noTransaction {
+		return m.db.Connection(func(conn *gorm.DB) error {
+			err := run(conn)
+			if resetErr := conn.Exec("RESET ALL").Error; err == nil && resetErr != nil {
+				return fmt.Errorf("failed to reset session after %s: %w", name, resetErr)
+			}
+			return err
+		})
+	}
+	return m.db.Transaction(run)
+}
+
+// migrationVersion returns the timestamp prefix of a migration filename.
+func migrationVersion(name string) string {
+	version, _, _ := strings.Cut(name, "_")
+	return version
+}
+
+func knownVersion(version string, applied []Migration, pending []string) bool {
+	for _, m := range applied {
+		if migrationVersion(m.Name) == version {
+			return true
+		}
+	}
+	for _, name := range pending {
+		if migrationVersion(name) == version {
+			return true
+		}
+	}
+	return false
+}
+
+// useTransaction reports whether the leading comment block of sql lacks the
+// no-transaction marker.
+func useTransaction(sql string) bool {
+	scanner := bufio.NewScanner(strings.NewReader(sql))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		if !strings.HasPrefix(line, "--") {
+			break
+		}
+		if line == noTransactionMarker {
+			return false
+		}
+	}
+	return true
+}
+
+// downName maps a migration's .up.sql filename to its .down.sql.
+func downName(upName string) (string, error) {
+	if !strings.HasSuffix(upName, ".up.sql") {
+		return "", fmt.Errorf("invalid migration filename format: %s", upName)
+	}
+	return strings.TrimSuffix(upName, ".up.sql") + ".down.sql", nil
+}
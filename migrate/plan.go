@@ -0,0 +1,198 @@
+package migrate
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"strings"
+	"time"
+)
+
+// PlanStep is a single migration a run would apply or roll back, together
+// with the SQL it would execute. Go migrations have no SQL.
+type PlanStep struct {
+	Name          string
+	File          string
+	SQL           string
+	Record        string // statement keeping the migrations table in sync
+	Setup         string // SQL the plan needs first, e.g. creating the migrations table
+	NoTransaction bool
+	Go            bool
+}
+
+// PlanUp computes the migrations Up(n) would apply without executing
+// anything or writing to the migrations table.
+func (m *Migrator) PlanUp(n int) ([]PlanStep, error) {
+	applied, err := m.loadAppliedReadOnly()
+	if err != nil {
+		return nil, err
+	}
+	pending, err := m.migrationsToApply(applied, n)
+	if err != nil {
+		return nil, err
+	}
+
+	setup := ""
+	if len(pending) > 0 && !m.db.Migrator().HasTable(m.opts.TableName) {
+		setup = m.createTableSQL()
+	}
+
+	batch := nextBatch(applied)
+	var steps []PlanStep
+	for i, name := range pending {
+		up, down, err := m.checksums(name)
+		if err != nil {
+			return nil, err
+		}
+		record := fmt.Sprintf(
+			"INSERT INTO %s (name, up_checksum, down_checksum, batch, applied_at) VALUES (%s, %s, %s, %d, now());",
+			m.opts.TableName, QuoteLiteral(name), QuoteLiteral(up), QuoteLiteral(down), batch,
+		)
+		var step PlanStep
+		if isGoMigration(name) {
+			step = PlanStep{Name: name, File: name, Record: record, Go: true}
+		} else if step, err = m.planStep(name, name, record); err != nil {
+			return nil, err
+		}
+		if i == 0 {
+			step.Setup = setup
+		}
+		steps = append(steps, step)
+	}
+	return steps, nil
+}
+
+// createTableSQL returns the DDL of the migrations table, as AutoMigrate
+// creates it from Migration.
+func (m *Migrator) createTableSQL() string {
+	t := m.opts.TableName
+	return fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %[1]s (
+    id            BIGSERIAL PRIMARY KEY,
+    name          TEXT,
+    up_checksum   TEXT,
+    down_checksum TEXT,
+    batch         BIGINT,
+    applied_at    TIMESTAMPTZ,
+    CONSTRAINT uni_%[1]s_name UNIQUE (name)
+);
+CREATE INDEX IF NOT EXISTS idx_%[1]s_batch ON %[1]s (batch);`, t)
+}
+
+// PlanDown computes the migrations Down(n) would undo without executing
+// anything or writing to the migrations table.
+func (m *Migrator) PlanDown(n int) ([]PlanStep, error) {
+	applied, err := m.loadAppliedReadOnly()
+	if err != nil {
+		return nil, err
+	}
+
+	var steps []PlanStep
+	for _, target := range migrationsToRollback(applied, n) {
+		record := fmt.Sprintf("DELETE FROM %s WHERE name = %s;", m.opts.TableName, QuoteLiteral(target.Name))
+		if isGoMigration(target.Name) {
+			if !m.hasDownFile(target.Name) {
+				return nil, &ErrMissingDown{Name: target.Name}
+			}
+			steps = append(steps, PlanStep{Name: target.Name, File: target.Name, Record: record, Go: true})
+			continue
+		}
+
+		down, err := downName(target.Name)
+		if err != nil {
+			return nil, err
+		}
+		step, err := m.planStep(target.Name, down, record)
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, &ErrMissingDown{Name: target.Name}
+		}
+		if err != nil {
+			return nil, err
+		}
+		steps = append(steps, step)
+	}
+	return steps, nil
+}
+
+// WritePlanScript writes steps as a single script that can be reviewed and
+// applied by hand. Migrations share one BEGIN/COMMIT block, except those
+// marked no-transaction, which run between blocks; the Setup of the steps
+// comes first, so the script also works on a database without a migrations
+// table. Plans containing Go migrations cannot be expressed as SQL and are
+// rejected.
+func WritePlanScript(w io.Writer, steps []PlanStep) error {
+	for _, step := range steps {
+		if step.Go {
+			return fmt.Errorf("plan includes Go migration %s, which cannot be written as SQL", step.Name)
+		}
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "-- Generated by gecho migrate --dry-run at %s\n", time.Now().Format(time.RFC3339))
+	fmt.Fprintf(&b, "-- %d migration(s)\n", len(steps))
+	for _, step := range steps {
+		if step.Setup != "" {
+			fmt.Fprintf(&b, "\n%s\n", step.Setup)
+		}
+	}
+	b.WriteString("\nBEGIN;\n")
+
+	for _, step := range steps {
+		if step.NoTransaction {
+			b.WriteString("\nCOMMIT;\n")
+		}
+		fmt.Fprintf(&b, "\n-- %s\n%s\n%s\n", step.File, terminate(step.SQL), step.Record)
+		if step.NoTransaction {
+			b.WriteString("\nBEGIN;\n")
+		}
+	}
+
+	b.WriteString("\nCOMMIT;\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// loadAppliedReadOnly mirrors loadApplied without creating the migrations
+// table, treating a missing table as nothing applied.
+func (m *Migrator) loadAppliedReadOnly() ([]Migration, error) {
+	var applied []Migration
+	if !m.db.Migrator().HasTable(m.opts.TableName) {
+		return applied, nil
+	}
+	if err := m.table().Order("name").Find(&applied).Error; err != nil {
+		return nil, fmt.Errorf("failed to read %s table: %w", m.opts.TableName, err)
+	}
+	if err := m.verifyChecksums(applied); err != nil {
+		return nil, err
+	}
+	return applied, nil
+}
+
+func (m *Migrator) planStep(name, file, record string) (PlanStep, error) {
+	sql, err := fs.ReadFile(m.fsys, file)
+	if err != nil {
+		return PlanStep{}, fmt.Errorf("failed to read %s: %w", file, err)
+	}
+	return PlanStep{
+		Name:          name,
+		File:          file,
+		SQL:           string(sql),
+		Record:        record,
+		NoTransaction: !useTransaction(string(sql)),
+	}, nil
+}
+
+// terminate trims trailing whitespace and makes sure the script's next
+// statement does not run into the last one of sql.
+func terminate(sql string) string {
+	sql = strings.TrimRight(sql, " \t\r\n")
+	if !strings.HasSuffix(sql, ";") {
+		sql += "\n;"
+	}
+	return sql
+}
+
+// QuoteLiteral returns s as a SQL string literal, doubling its quotes.
+func QuoteLiteral(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
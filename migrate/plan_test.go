@@ -0,0 +1,32 @@
+package migrate
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestWritePlanScriptSetup(t *testing.T) {
+	m := New(nil, nil, Options{})
+	steps := []PlanStep{
+		{Name: "1_a.up.sql", File: "1_a.up.sql", SQL: "CREATE TABLE a (id int);", Record: "INSERT INTO migrations ...;", Setup: m.createTableSQL()},
+		{Name: "2_b.up.sql", File: "2_b.up.sql", SQL: "CREATE TABLE b (id int);", Record: "INSERT INTO migrations ...;"},
+	}
+
+	var b strings.Builder
+	if err := WritePlanScript(&b, steps); err != nil {
+		t.Fatal(err)
+	}
+	script := b.String()
+	create := strings.Index(script, "CREATE TABLE IF NOT EXISTS migrations (")
+	begin := strings.Index(script, "BEGIN;")
+	if create < 0 || begin < 0 || create > begin {
+		t.Errorf("migrations table is not created before the first BEGIN:\n%s", script)
+	}
+	if n := strings.Count(script, "CREATE TABLE IF NOT EXISTS"); n != 1 {
+		t.Errorf("script creates the migrations table %d times:\n%s", n, script)
+	}
+	if !strings.Contains(script, "CONSTRAINT uni_migrations_name UNIQUE (name)") ||
+		!strings.Contains(script, "CREATE INDEX IF NOT EXISTS idx_migrations_batch ON migrations (batch);") {
+		t.Errorf("migrations table does not match Migration:\n%s", script)
+	}
+}
@@ -0,0 +1,102 @@
+package migrate
+
+import (
+	"fmt"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"gorm.io/gorm"
+)
+
+// GoMigrationFunc is the Up or Down step of a Go migration. It runs inside
+// the same transaction that records the migration.
+type GoMigrationFunc func(tx *gorm.DB) error
+
+type goMigration struct {
+	up, down GoMigrationFunc
+}
+
+var (
+	registryMu sync.Mutex
+	registry   = make(map[string]goMigration)
+)
+
+// goMigrationFile matches Go migration files, named like the SQL ones:
+// <timestamp>_<name>.go.
+var goMigrationFile = regexp.MustCompile(`^\d+_.+\.go$`)
+
+// Register adds a Go migration keyed by the same <timestamp>_<name> used for
+// SQL files, e.g. "20250101120000_backfill_slugs". It is meant to be called
+// from the init func of the generated migration file. down may be nil if
+// the migration cannot be rolled back. Register panics on duplicate names.
+func Register(name string, up, down GoMigrationFunc) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	if up == nil {
+		panic("migrate: Register " + name + " with nil up func")
+	}
+	if !goMigrationFile.MatchString(name + ".go") {
+		panic("migrate: Register " + name + ": name must look like <timestamp>_<name>")
+	}
+	if _, dup := registry[name]; dup {
+		panic("migrate: Register called twice for " + name)
+	}
+	registry[name] = goMigration{up: up, down: down}
+}
+
+// HasRegistered reports whether any Go migrations were registered in this
+// binary.
+func HasRegistered() bool {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	return len(registry) > 0
+}
+
+// lookupGo returns the Go migration recorded under name ("<key>.go").
+func lookupGo(name string) (goMigration, bool) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	g, ok := registry[strings.TrimSuffix(name, ".go")]
+	return g, ok
+}
+
+// registeredNames lists registered Go migrations as recorded in the
+// migrations table, i.e. with a .go suffix.
+func registeredNames() []string {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	names := make([]string, 0, len(registry))
+	for key := range registry {
+		names = append(names, key+".go")
+	}
+	sort.Strings(names)
+	return names
+}
+
+// checkRegistered fails if fsys holds Go migration files that were never
+// registered, which means the package containing them was not imported.
+func checkRegistered(fsys fs.FS) error {
+	files, err := fs.Glob(fsys, "*.go")
+	if err != nil {
+		return fmt.Errorf("failed to read migration files: %w", err)
+	}
+	for _, file := range files {
+		if !goMigrationFile.MatchString(file) {
+			continue
+		}
+		if _, ok := lookupGo(file); !ok {
+			return fmt.Errorf("go migration %s is not registered; import its package "+
+				"(e.g. _ \"<module>/db/migrations\") in the binary running migrations", file)
+		}
+	}
+	return nil
+}
+
+func isGoMigration(name string) bool {
+	return strings.HasSuffix(name, ".go")
+}
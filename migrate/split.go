@@ -0,0 +1,179 @@
+package migrate
+
+import "strings"
+
+// Statement is a single SQL statement of a migration file.
+type Statement struct {
+	SQL  string
+	Line int // 1-based line of the file the statement starts on
+}
+
+// SplitStatements splits a migration file on semicolons, ignoring those
+// inside string literals, quoted identifiers, dollar-quoted bodies and
+// comments. Comments preceding a statement and statements containing only
+// comments are dropped.
+func SplitStatements(src string) []Statement {
+	var (
+		stmts       []Statement
+		current     strings.Builder
+		line        = 1
+		startLine   = 0 // line of the first non-comment token, 0 until seen
+		startOffset = 0 // its offset in current; leading comments are dropped
+		significant = func() {
+			if startLine == 0 {
+				startLine = line
+				startOffset = current.Len()
+			}
+		}
+	)
+
+	// copyTo appends src[i:j] to the current statement, counting lines.
+	copyTo := func(i, j int) int {
+		chunk := src[i:j]
+		line += strings.Count(chunk, "\n")
+		current.WriteString(chunk)
+		return j
+	}
+
+	flush := func() {
+		if startLine != 0 {
+			sql := strings.TrimSpace(current.String()[startOffset:])
+			stmts = append(stmts, Statement{SQL: sql, Line: startLine})
+		}
+		current.Reset()
+		startLine = 0
+	}
+
+	for i := 0; i < len(src); {
+		c := src[i]
+		switch {
+		case c == '-' && strings.HasPrefix(src[i:], "--"):
+			end := strings.IndexByte(src[i:], '\n')
+			if end < 0 {
+				end = len(src) - i
+			}
+			i = copyTo(i, i+end)
+
+		case c == '/' && strings.HasPrefix(src[i:], "/*"):
+			i = copyTo(i, blockCommentEnd(src, i))
+
+		case c == '\'':
+			significant()
+			i = copyTo(i, stringEnd(src, i, isEscapeString(src, i)))
+
+		case c == '"':
+			significant()
+			i = copyTo(i, quotedIdentEnd(src, i))
+
+		case c == '$':
+			significant()
+			if tag, ok := dollarTag(src, i); ok {
+				end := strings.Index(src[i+len(tag):], tag)
+				if end < 0 {
+					i = copyTo(i, len(src))
+				} else {
+					i = copyTo(i, i+len(tag)+end+len(tag))
+				}
+			} else {
+				i = copyTo(i, i+1)
+			}
+
+		case c == ';':
+			flush()
+			i++
+
+		default:
+			if c != ' ' && c != '\t' && c != '\n' && c != '\r' {
+				significant()
+			}
+			i = copyTo(i, i+1)
+		}
+	}
+	flush()
+	return stmts
+}
+
+// blockCommentEnd returns the index just past the (possibly nested) block
+// comment starting at i.
+func blockCommentEnd(src string, i int) int {
+	depth := 0
+	for i < len(src) {
+		switch {
+		case strings.HasPrefix(src[i:], "/*"):
+			depth++
+			i += 2
+		case strings.HasPrefix(src[i:], "*/"):
+			depth--
+			i += 2
+			if depth == 0 {
+				return i
+			}
+		default:
+			i++
+		}
+	}
+	return len(src)
+}
+
+// stringEnd returns the index just past the string literal starting at i.
+// Doubled quotes are always escapes; backslashes only in E'...' strings.
+func stringEnd(src string, i int, backslashEscapes bool) int {
+	for i++; i < len(src); i++ {
+		switch {
+		case backslashEscapes && src[i] == '\\':
+			i++
+		case src[i] == '\'':
+			if i+1 < len(src) && src[i+1] == '\'' {
+				i++
+				continue
+			}
+			return i + 1
+		}
+	}
+	return len(src)
+}
+
+// isEscapeString reports whether the quote at i opens an E'...' string.
+func isEscapeString(src string, i int) bool {
+	if i == 0 || (src[i-1] != 'E' && src[i-1] != 'e') {
+		return false
+	}
+	return i == 1 || !isIdentChar(src[i-2])
+}
+
+func quotedIdentEnd(src string, i int) int {
+	for i++; i < len(src); i++ {
+		if src[i] == '"' {
+			if i+1 < len(src) && src[i+1] == '"' {
+				i++
+				continue
+			}
+			return i + 1
+		}
+	}
+	return len(src)
+}
+
+// dollarTag returns the $tag$ opening a dollar-quoted string at i, if any.
+// Positional parameters ($1) and identifiers containing $ do not count.
+func dollarTag(src string, i int) (string, bool) {
+	if i > 0 && isIdentChar(src[i-1]) {
+		return "", false
+	}
+	for j := i + 1; j < len(src); j++ {
+		c := src[j]
+		switch {
+		case c == '$':
+			return src[i : j+1], true
+		case c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z':
+		case c >= '0' && c <= '9' && j > i+1:
+		default:
+			return "", false
+		}
+	}
+	return "", false
+}
+
+func isIdentChar(c byte) bool {
+	return c == '_' || c == '$' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c >= 0x80
+}
@@ -0,0 +1,103 @@
+package migrate
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSplitStatements(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want []Statement
+	}{
+		{
+			name: "statements and lines",
+			src:  "CREATE TABLE a (id int);\n\nCREATE TABLE b (id int);\n",
+			want: []Statement{{"CREATE TABLE a (id int)", 1}, {"CREATE TABLE b (id int)", 3}},
+		},
+		{
+			name: "semicolon in string and quoted identifier",
+			src:  `INSERT INTO "a;b" VALUES ('x;y', 'it''s;');`,
+			want: []Statement{{`INSERT INTO "a;b" VALUES ('x;y', 'it''s;')`, 1}},
+		},
+		{
+			name: "backslash escapes only in E strings",
+			src:  `SELECT E'it\'s;'; SELECT 'C:\'; SELECT 2;`,
+			want: []Statement{{`SELECT E'it\'s;'`, 1}, {`SELECT 'C:\'`, 1}, {"SELECT 2", 1}},
+		},
+		{
+			name: "dollar-quoted body with tag",
+			src: "CREATE FUNCTION f() RETURNS trigger AS $body$\nBEGIN\n  NEW.x := 1; RETURN NEW;\nEND;\n$body$ LANGUAGE plpgsql;\n" +
+				"SELECT 1;",
+			want: []Statement{
+				{"CREATE FUNCTION f() RETURNS trigger AS $body$\nBEGIN\n  NEW.x := 1; RETURN NEW;\nEND;\n$body$ LANGUAGE plpgsql", 1},
+				{"SELECT 1", 6},
+			},
+		},
+		{
+			name: "other dollar tags inside a dollar-quoted body",
+			src:  "DO $outer$ BEGIN EXECUTE $$SELECT ';'$$; END $outer$;",
+			want: []Statement{{"DO $outer$ BEGIN EXECUTE $$SELECT ';'$$; END $outer$", 1}},
+		},
+		{
+			name: "positional parameters are not dollar tags",
+			src:  "PREPARE p AS SELECT $1, $2; EXECUTE p(1, 2);",
+			want: []Statement{{"PREPARE p AS SELECT $1, $2", 1}, {"EXECUTE p(1, 2)", 1}},
+		},
+		{
+			name: "nested block comments",
+			src:  "/* outer /* inner; */ still; */\nSELECT 1; /* a; /* b; */ c; */ SELECT 2;",
+			want: []Statement{{"SELECT 1", 2}, {"SELECT 2", 2}},
+		},
+		{
+			name: "leading line comments are dropped",
+			src:  "-- create a; b\n-- and c;\nCREATE TABLE c (id int);",
+			want: []Statement{{"CREATE TABLE c (id int)", 3}},
+		},
+		{
+			name: "trailing line comment",
+			src:  "SELECT 1; -- done;",
+			want: []Statement{{"SELECT 1", 1}},
+		},
+		{
+			name: "trailing line comment without semicolon",
+			src:  "SELECT 1 -- no semicolon",
+			want: []Statement{{"SELECT 1 -- no semicolon", 1}},
+		},
+		{
+			name: "comments only",
+			src:  "-- SQL UP Migration\n/* nothing; */\n",
+			want: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := SplitStatements(tt.src); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("SplitStatements(%q) =\n%q\nwant\n%q", tt.src, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUseTransaction(t *testing.T) {
+	tests := []struct {
+		sql  string
+		want bool
+	}{
+		{"CREATE TABLE a (id int);", true},
+		{"-- SQL UP Migration\nCREATE TABLE a (id int);", true},
+		{noTransactionMarker + "\nCREATE INDEX CONCURRENTLY i ON a (id);", false},
+		{"\n-- SQL UP Migration\n  " + noTransactionMarker + "  \nCREATE INDEX CONCURRENTLY i ON a (id);", false},
+		{"CREATE TABLE a (id int);\n" + noTransactionMarker + "\n", true},
+		{"-- " + noTransactionMarker[3:] + " please\nSELECT 1;", true},
+		{"", true},
+	}
+
+	for _, tt := range tests {
+		if got := useTransaction(tt.sql); got != tt.want {
+			t.Errorf("useTransaction(%q) = %v, want %v", tt.sql, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package migrate
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// MigrationState describes where a migration stands relative to the database.
+type MigrationState string
+
+const (
+	StateApplied  MigrationState = "applied"
+	StatePending  MigrationState = "pending"
+	StateOrphaned MigrationState = "orphaned" // recorded in the database, file is gone
+)
+
+// MigrationStatus is a single migration as reported by `gecho migrate status`.
+type MigrationStatus struct {
+	Name      string         `json:"name"`
+	State     MigrationState `json:"state"`
+	AppliedAt *time.Time     `json:"applied_at,omitempty"`
+	Batch     int            `json:"batch,omitempty"`
+	HasDown   bool           `json:"has_down"`
+	Modified  bool           `json:"modified"` // files changed since the migration was applied
+}
+
+// Status joins the migration files against the migrations table without
+// modifying either.
+func (m *Migrator) Status() ([]MigrationStatus, error) {
+	var applied []Migration
+	if m.db.Migrator().HasTable(m.opts.TableName) {
+		if err := m.table().Find(&applied).Error; err != nil {
+			return nil, fmt.Errorf("failed to read %s table: %w", m.opts.TableName, err)
+		}
+	}
+
+	files, err := m.upFiles()
+	if err != nil {
+		return nil, err
+	}
+
+	onDisk := make(map[string]bool)
+	for _, name := range files {
+		onDisk[name] = true
+	}
+
+	var statuses []MigrationStatus
+	recorded := make(map[string]bool)
+	for _, a := range applied {
+		modified, err := m.hasDrifted(a)
+		if err != nil {
+			return nil, err
+		}
+		appliedAt := a.AppliedAt
+		state := StateApplied
+		if !onDisk[a.Name] {
+			state = StateOrphaned
+		}
+		recorded[a.Name] = true
+		statuses = append(statuses, MigrationStatus{
+			Name:      a.Name,
+			State:     state,
+			AppliedAt: &appliedAt,
+			Batch:     a.Batch,
+			HasDown:   m.hasDownFile(a.Name),
+			Modified:  modified,
+		})
+	}
+
+	for name := range onDisk {
+		if recorded[name] {
+			continue
+		}
+		statuses = append(statuses, MigrationStatus{
+			Name:    name,
+			State:   StatePending,
+			HasDown: m.hasDownFile(name),
+		})
+	}
+
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Name < statuses[j].Name })
+	return statuses, nil
+}
+
+func (m *Migrator) hasDownFile(upName string) bool {
+	if isGoMigration(upName) {
+		g, ok := lookupGo(upName)
+		return ok && g.down != nil
+	}
+	down, err := downName(upName)
+	return err == nil && m.fileExists(down)
+}
@@ -0,0 +1,48 @@
+package seed
+
+import (
+	"path"
+	"regexp"
+	"sync"
+
+	"gorm.io/gorm"
+)
+
+// Func is a Go seed. It runs inside the transaction that records it.
+type Func func(tx *gorm.DB) error
+
+var (
+	registryMu sync.Mutex
+	registry   = make(map[string]Func)
+)
+
+// goSeedFile matches Go seed files: <order>_<name>.go.
+var goSeedFile = regexp.MustCompile(`^\d+_.+\.go$`)
+
+// Register adds a Go seed keyed by its file's path relative to the seeds
+// directory without the .go suffix, e.g. "0002_admin_user", or
+// "development/0002_admin_user" for an environment override. It is meant
+// to be called from the init func of the seed file and panics on
+// duplicate names.
+func Register(name string, fn Func) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	if fn == nil {
+		panic("seed: Register " + name + " with nil func")
+	}
+	if !goSeedFile.MatchString(path.Base(name) + ".go") {
+		panic("seed: Register " + name + ": name must look like <order>_<name>")
+	}
+	if _, dup := registry[name]; dup {
+		panic("seed: Register called twice for " + name)
+	}
+	registry[name] = fn
+}
+
+func lookup(name string) (Func, bool) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	fn, ok := registry[name]
+	return fn, ok
+}
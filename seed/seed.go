@@ -0,0 +1,201 @@
+// Package seed loads seed data into PostgreSQL from .sql files and
+// registered Go functions. It is the engine behind `gecho db seed`.
+//
+// Seeds run once per database in filename order and are recorded in the
+// seeds table, so running them again does not duplicate rows. A seed in
+// the <env>/ sub-directory replaces the seed of the same name for that
+// environment, and seeds only found there are added to it.
+package seed
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/Juksefantomet/gecho/migrate"
+)
+
+// DefaultTableName is the table tracking seeds that already ran.
+const DefaultTableName = "seeds"
+
+// Seed represents a seed record in the database.
+type Seed struct {
+	ID       uint   `gorm:"primaryKey"`
+	Name     string `gorm:"unique"` // path relative to the seeds directory
+	Checksum string
+	SeededAt time.Time
+}
+
+// Options configures a Seeder. The zero value is ready to use.
+type Options struct {
+	// Env selects the <env>/ sub-directory whose seeds override and extend
+	// the shared ones. Empty means the shared seeds only.
+	Env string
+	// TableName is the table tracking seeds that already ran. Defaults to
+	// DefaultTableName.
+	TableName string
+	// Logger receives progress messages. Defaults to log.Default().
+	Logger migrate.Logger
+}
+
+// Seeder runs the .sql files and registered Go seeds of a file system,
+// tracking them in the seeds table.
+type Seeder struct {
+	db   *gorm.DB
+	fsys fs.FS
+	opts Options
+}
+
+// New returns a Seeder reading seed files from the root of fsys, e.g.
+// os.DirFS("db/seeds").
+func New(db *gorm.DB, fsys fs.FS, opts Options) *Seeder {
+	if opts.TableName == "" {
+		opts.TableName = DefaultTableName
+	}
+	if opts.Logger == nil {
+		opts.Logger = log.Default()
+	}
+	return &Seeder{db: db, fsys: fsys, opts: opts}
+}
+
+// Run executes every seed that has not run yet against this database and
+// returns how many ran. Seeds modified after they ran are reported and
+// skipped, since running them again could duplicate their rows.
+func (s *Seeder) Run() (int, error) {
+	files, err := s.files()
+	if err != nil {
+		return 0, err
+	}
+	if err := s.db.Table(s.opts.TableName).AutoMigrate(&Seed{}); err != nil {
+		return 0, fmt.Errorf("failed to create %s table: %w", s.opts.TableName, err)
+	}
+
+	var done []Seed
+	if err := s.db.Table(s.opts.TableName).Find(&done).Error; err != nil {
+		return 0, fmt.Errorf("failed to read %s table: %w", s.opts.TableName, err)
+	}
+	checksums := make(map[string]string, len(done))
+	for _, d := range done {
+		checksums[d.Name] = d.Checksum
+	}
+
+	ran := 0
+	for _, file := range files {
+		data, err := fs.ReadFile(s.fsys, file)
+		if err != nil {
+			return ran, fmt.Errorf("failed to read %s: %w", file, err)
+		}
+		sum := sha256.Sum256(data)
+		checksum := hex.EncodeToString(sum[:])
+
+		if previous, ok := checksums[file]; ok {
+			if previous != checksum {
+				s.opts.Logger.Printf("Skipping seed %s: modified since it ran", file)
+			}
+			continue
+		}
+
+		body, err := s.body(file, string(data))
+		if err != nil {
+			return ran, err
+		}
+		s.opts.Logger.Printf("Seeding: %s", file)
+		err = s.db.Transaction(func(tx *gorm.DB) error {
+			if err := body(tx); err != nil {
+				return err
+			}
+			record := Seed{Name: file, Checksum: checksum, SeededAt: time.Now()}
+			return tx.Table(s.opts.TableName).Create(&record).Error
+		})
+		if err != nil {
+			return ran, err
+		}
+		ran++
+	}
+	return ran, nil
+}
+
+// files lists the seeds to run in order: the shared ones at the root of
+// fsys, with those of the same name in the Env directory taking their place.
+func (s *Seeder) files() ([]string, error) {
+	byName := make(map[string]string)
+	for _, dir := range []string{".", s.opts.Env} {
+		if dir == "" {
+			continue
+		}
+		entries, err := fs.ReadDir(s.fsys, dir)
+		if errors.Is(err, fs.ErrNotExist) && dir != "." {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read seed files: %w", err)
+		}
+		for _, e := range entries {
+			if e.IsDir() || !isSeedFile(e.Name()) {
+				continue
+			}
+			key := strings.TrimSuffix(e.Name(), path.Ext(e.Name()))
+			byName[key] = path.Join(dir, e.Name())
+		}
+	}
+
+	keys := make([]string, 0, len(byName))
+	for key := range byName {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	files := make([]string, len(keys))
+	for i, key := range keys {
+		files[i] = byName[key]
+	}
+	return files, nil
+}
+
+// body returns the step running the seed file.
+func (s *Seeder) body(file, src string) (func(tx *gorm.DB) error, error) {
+	if path.Ext(file) == ".go" {
+		fn, ok := lookup(strings.TrimSuffix(file, ".go"))
+		if !ok {
+			return nil, fmt.Errorf("go seed %s is not registered; import its package "+
+				"in the binary running seeds", file)
+		}
+		return func(tx *gorm.DB) error {
+			if err := fn(tx); err != nil {
+				return fmt.Errorf("seed %s failed: %w", file, err)
+			}
+			return nil
+		}, nil
+	}
+
+	return func(tx *gorm.DB) error {
+		for i, stmt := range migrate.SplitStatements(src) {
+			if err := tx.Exec(stmt.SQL).Error; err != nil {
+				return fmt.Errorf("seed %s failed at statement %d (line %d): %w", file, i+1, stmt.Line, err)
+			}
+		}
+		return nil
+	}, nil
+}
+
+// isSeedFile reports whether name is a .sql seed or a Go seed named like
+// one, <order>_<name>.go, leaving other Go files free for helpers.
+func isSeedFile(name string) bool {
+	switch {
+	case strings.HasSuffix(name, ".sql"):
+		return true
+	case strings.HasSuffix(name, "_test.go"):
+		return false
+	default:
+		return goSeedFile.MatchString(name)
+	}
+}